@@ -34,8 +34,6 @@ func StartClient(logger zerolog.Logger) {
 	msg := &dto.LiveChatSocketEvent{}
 	authenticated := false
 
-	connectedRoomName := ""
-
 	for !authenticated {
 		fmt.Printf("1. Login\n2. Sign Up\n9. Exit\n")
 		fmt.Printf("Option: ")
@@ -144,8 +142,6 @@ func StartClient(logger zerolog.Logger) {
 				logger.Error().Err(err).Send()
 			}
 
-			fmt.Printf("room")
-			connectedRoomName = roomName
 			client.writerChan <- dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatJoinRoomEvent,
 				Data:      roomName,
@@ -155,7 +151,6 @@ func StartClient(logger zerolog.Logger) {
 			fmt.Printf("Room name: ")
 			fmt.Scanf("%s\n", &roomName)
 
-			connectedRoomName = ""
 			client.writerChan <- dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatLeaveRoomEvent,
 				Data:      roomName,
@@ -172,23 +167,23 @@ func StartClient(logger zerolog.Logger) {
 				EventName: inconst.LiveChatSendDirectMsgEvent,
 				Data: dto.ChatDMPayload{
 					RecipientUsername: recipient,
-					Content:           content,
+					MessageEnvelope:   dto.MessageEnvelope{Content: content},
 				},
 			}
 		case 5:
-			if connectedRoomName == "" {
-				continue
-			}
-
-			fmt.Printf("Current Connected Room: %s\n", connectedRoomName)
+			var roomName, content string
+			fmt.Printf("Room name: ")
+			fmt.Scanf("%s\n", &roomName)
 
-			var content string
 			fmt.Printf("Content: ")
 			fmt.Scanf("%s\n", &content)
 
 			client.writerChan <- dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatSendRoomMsgEvent,
-				Data:      content,
+				Data: dto.ChatRoomPayload{
+					RoomName:        roomName,
+					MessageEnvelope: dto.MessageEnvelope{Content: content},
+				},
 			}
 		case 9:
 			exit = true
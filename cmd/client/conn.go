@@ -21,7 +21,7 @@ type LiveClient struct {
 func (lc *LiveClient) reader() {
 	defer lc.conn.Close()
 
-	lc.conn.SetReadLimit(256)
+	lc.conn.SetReadLimit(4096)
 	lc.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	lc.conn.SetPongHandler(func(string) error { lc.conn.SetReadDeadline(time.Now().Add(60 * time.Second)); return nil })
 
@@ -39,7 +39,7 @@ func (lc *LiveClient) reader() {
 			continue
 		case inconst.LiveChatIncomingMsgEvent:
 			meta := structutil.MapToStruct[*indto.IncomingMessage](event.Data.(map[string]any))
-			lc.logger.Info().Str("Sender", meta.SenderName).Str("Content", meta.Content).Bool("FromDM", meta.IsDM).Msg(meta.Content)
+			lc.logger.Info().Str("Sender", meta.SenderName).Str("Room", meta.RoomName).Bool("FromDM", meta.IsDM).Msg(meta.Content)
 			continue
 		case inconst.LiveChatCreateRoomEvent:
 			lc.logger.Info().Msg("room created")
@@ -50,6 +50,9 @@ func (lc *LiveClient) reader() {
 		case inconst.LiveChatLeftEvent:
 			lc.logger.Info().Msg("room left")
 			continue
+		case inconst.LiveChatRoomListEvent:
+			lc.logger.Info().Interface("Rooms", event.Data).Msg("joined rooms")
+			continue
 		}
 
 	}
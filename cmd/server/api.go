@@ -0,0 +1,176 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func sessionUser(c echo.Context) *model.User {
+	return c.Get(ctxKeyUser).(*model.User)
+}
+
+func paramID(c echo.Context, name string) (id int64, err error) {
+	id, err = strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errs.ErrBadRequest
+	}
+
+	return
+}
+
+// HandleListRooms list every discoverable room, or only rooms joined by the user when `joined=true`
+func HandleListRooms(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		// private room is only listed to its members
+		roomParams := &indto.ChatRoomParams{Visibilities: []string{inconst.RoomVisibilityPublic, inconst.RoomVisibilityInviteOnly}}
+		if c.QueryParam("joined") == "true" {
+			roomParams = &indto.ChatRoomParams{UserID: sessionUser(c).ID}
+		}
+
+		rooms, err := params.Repo.FindRooms(ctx, roomParams)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch rooms")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: toRoomInfos(rooms)})
+	}
+}
+
+func HandleCreateRoom(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		payload := &dto.CreateRoomPayload{}
+		if err = c.Bind(payload); err != nil || payload.RoomName == "" {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		roomMeta, err := createRoom(ctx, params.Repo, params.Hub, sessionUser(c), payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to create room data")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, dto.BaseResponse{Data: toRoomInfos([]*model.ChatRoom{roomMeta})[0]})
+	}
+}
+
+func HandleRoomHistory(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		roomID, err := paramID(c, "room_id")
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		roomMeta, err := findMemberRoom(ctx, params.Repo, &indto.ChatRoomParams{ID: roomID}, sessionUser(c).ID)
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		payload := &dto.ChatLogPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+		normalizeChatLogLimit(payload)
+
+		res, err := fetchChatLog(ctx, params.Repo, &indto.ChatHistoryParams{RoomID: roomMeta.ID}, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to get message log")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
+
+// HandlePostRoomMessage post message on behalf of the user, message is broadcasted to live room member as usual
+func HandlePostRoomMessage(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+		user := sessionUser(c)
+
+		roomID, err := paramID(c, "room_id")
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		roomMeta, err := findMemberRoom(ctx, params.Repo, &indto.ChatRoomParams{ID: roomID}, user.ID)
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		payload := &dto.ChatRoomPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		res, err := postMessage(ctx, params.Repo, params.Hub, &outgoingMessage{
+			Sender:        user,
+			Room:          roomMeta,
+			Envelope:      payload.MessageEnvelope,
+			AttachmentIDs: payload.AttachmentIDs,
+		})
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to save message")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, dto.BaseResponse{Data: res})
+	}
+}
+
+func HandleDirectHistory(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		peerMeta, err := params.Repo.FindUser(ctx, &indto.UserParams{Username: c.Param("username")})
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch recipient meta")
+			return writeError(c, err)
+		} else if peerMeta == nil {
+			return writeError(c, errs.ErrNotFound)
+		}
+
+		payload := &dto.ChatLogPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+		normalizeChatLogLimit(payload)
+
+		res, err := fetchChatLog(ctx, params.Repo, &indto.ChatHistoryParams{UserID: sessionUser(c).ID, PeerID: peerMeta.ID, IsDM: true}, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to get message log")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
+
+func HandleFindUser(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		userMeta, err := params.Repo.FindUser(ctx, &indto.UserParams{Username: c.Param("username")})
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch userdata")
+			return writeError(c, err)
+		} else if userMeta == nil {
+			return writeError(c, errs.ErrNotFound)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: &indto.UserInfo{ID: userMeta.ID, Username: userMeta.Username}})
+	}
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/component"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+const (
+	maxAttachmentSize        = 10 << 20
+	maxAttachmentsPerMessage = 10
+	maxAttachmentNameLength  = 255
+	attachmentSniffLength    = 512 // http.DetectContentType never read past this
+)
+
+// allowedAttachmentTypes is matched against the sniffed content, the client declared type is never trusted
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+func attachmentURL(attachmentID int64) string {
+	return fmt.Sprintf("/api/v1/attachments/%d", attachmentID)
+}
+
+func toAttachmentInfos(attachments []*model.Attachment) (res []*indto.AttachmentInfo) {
+	res = []*indto.AttachmentInfo{}
+	for _, a := range attachments {
+		res = append(res, &indto.AttachmentInfo{
+			ID:          a.ID,
+			FileName:    a.FileName,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			URL:         attachmentURL(a.ID),
+		})
+	}
+
+	return
+}
+
+// sanitizeFileName keep only the base name supplied by the client, it is used for display and download name only
+func sanitizeFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+
+	if len(name) > maxAttachmentNameLength {
+		name = name[:maxAttachmentNameLength]
+	}
+
+	return name
+}
+
+// storeAttachment validate and persist uploaded file, content is addressed by its hash so identical upload share a single blob
+func storeAttachment(ctx context.Context, repo inrepo.Repository, store component.BlobStore, uploader *model.User, header *multipart.FileHeader) (res *model.Attachment, err error) {
+	if header.Size <= 0 || header.Size > maxAttachmentSize {
+		return nil, errs.ErrBadRequest
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, errs.ErrBadRequest
+	}
+	defer file.Close()
+
+	head := make([]byte, attachmentSniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errs.ErrBadRequest
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil || !allowedAttachmentTypes[contentType] {
+		return nil, errs.ErrBadRequest
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// declared size is reported by the client, enforce the limit on the actual content as well
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	} else if size > maxAttachmentSize {
+		return nil, errs.ErrBadRequest
+	}
+
+	res = &model.Attachment{
+		UploaderID:  uploader.ID,
+		FileName:    sanitizeFileName(header.Filename),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt:   time.Now(),
+	}
+
+	// mark the blob as used before checking the store so the content is kept for the grace period, blob already tombstoned by
+	// the collector may lose its content any moment thus the upload is stored apart under its own key
+	usable, err := repo.UpsertBlob(ctx, &model.Blob{StorageKey: res.StorageKey, Size: size, CreatedAt: res.CreatedAt, LastUsedAt: res.CreatedAt})
+	if err != nil {
+		return nil, err
+	} else if !usable {
+		res.StorageKey = fmt.Sprintf("%s-%s", res.StorageKey, strconv.FormatInt(res.CreatedAt.UnixNano(), 36))
+		if _, err = repo.UpsertBlob(ctx, &model.Blob{StorageKey: res.StorageKey, Size: size, CreatedAt: res.CreatedAt, LastUsedAt: res.CreatedAt}); err != nil {
+			return nil, err
+		}
+	}
+
+	exists, err := store.Exists(ctx, res.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		if err = store.Put(ctx, res.StorageKey, file, size); err != nil {
+			return nil, err
+		}
+	}
+
+	res.ID, err = repo.InsertAttachment(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// findUsableAttachments ensure every attachment were uploaded by the sender and hasn't been sent with other message
+func findUsableAttachments(ctx context.Context, repo inrepo.Repository, uploaderID int64, attachmentIDs []int64) (res []*model.Attachment, err error) {
+	attachmentIDs = slices.Clone(attachmentIDs)
+	slices.Sort(attachmentIDs)
+	attachmentIDs = slices.Compact(attachmentIDs)
+
+	if len(attachmentIDs) > maxAttachmentsPerMessage {
+		return nil, errs.ErrBadRequest
+	}
+
+	res, err = repo.FindAttachments(ctx, &indto.AttachmentParams{IDs: attachmentIDs, UploaderID: uploaderID, Unlinked: true})
+	if err != nil {
+		return nil, err
+	} else if len(res) != len(attachmentIDs) {
+		return nil, errs.ErrBadRequest
+	}
+
+	return
+}
+
+// loadAttachments fill attachment metadata of the messages, attachment of deleted message is hidden along with its content
+func loadAttachments(ctx context.Context, repo inrepo.Repository, msgs []*indto.IncomingMessage) (err error) {
+	msgIDs := []int64{}
+	for _, m := range msgs {
+		if m.DeletedAt == nil {
+			msgIDs = append(msgIDs, m.ID)
+		}
+	}
+
+	if len(msgIDs) == 0 {
+		return
+	}
+
+	attachments, err := repo.FindAttachments(ctx, &indto.AttachmentParams{MessageIDs: msgIDs})
+	if err != nil {
+		return
+	}
+
+	for _, m := range msgs {
+		for _, a := range attachments {
+			if a.MessageID == m.ID {
+				m.Attachments = append(m.Attachments, toAttachmentInfos([]*model.Attachment{a})...)
+			}
+		}
+	}
+
+	return
+}
+
+// findAccessibleAttachment ensure the user could read the message the attachment were sent with,
+// unsent upload is only visible to its uploader
+func findAccessibleAttachment(ctx context.Context, repo inrepo.Repository, attachmentID int64, userID int64) (res *model.Attachment, err error) {
+	res, err = repo.FindAttachment(ctx, &indto.AttachmentParams{ID: attachmentID})
+	if err != nil {
+		return nil, err
+	} else if res == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	if res.MessageID == 0 {
+		if res.UploaderID != userID {
+			return nil, errs.ErrNotFound
+		}
+		return
+	}
+
+	msg, err := findAccessibleMessage(ctx, repo, res.MessageID, userID)
+	if errors.Is(err, errs.ErrForbidden) || (err == nil && msg.DeletedAt != nil) {
+		return nil, errs.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// HandleUploadAttachment store a single file sent as multipart `file` field, the returned id is then sent along with a message
+func HandleUploadAttachment(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		// leave some room for multipart framing on top of the file itself
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxAttachmentSize+1<<20)
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		attachment, err := storeAttachment(ctx, params.Repo, params.Blob, sessionUser(c), header)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to store attachment")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, dto.BaseResponse{Data: toAttachmentInfos([]*model.Attachment{attachment})[0]})
+	}
+}
+
+// HandleDownloadAttachment serve attachment content, image is displayed inline while other type is always downloaded
+func HandleDownloadAttachment(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		attachmentID, err := paramID(c, "attachment_id")
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		attachment, err := findAccessibleAttachment(ctx, params.Repo, attachmentID, sessionUser(c).ID)
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		blob, err := params.Blob.Get(ctx, attachment.StorageKey)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to open attachment")
+			return writeError(c, err)
+		}
+		defer blob.Close()
+
+		disposition := "attachment"
+		if strings.HasPrefix(attachment.ContentType, "image/") {
+			disposition = "inline"
+		}
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentType, attachment.ContentType)
+		header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+		header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+		header.Set("Cache-Control", "private")
+
+		// seekable blob support range request, otherwise the content is streamed as is
+		if seeker, ok := blob.(io.ReadSeeker); ok {
+			http.ServeContent(c.Response(), c.Request(), attachment.FileName, attachment.CreatedAt, seeker)
+			return nil
+		}
+
+		return c.Stream(http.StatusOK, attachment.ContentType, blob)
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// insertTestAttachment store unsent upload metadata of the user, the blob itself is never read
+func insertTestAttachment(t *testing.T, repo inrepo.Repository, uploader *model.User) int64 {
+	t.Helper()
+
+	id, err := repo.InsertAttachment(testContext(), &model.Attachment{
+		UploaderID: uploader.ID, FileName: "a.png", ContentType: "image/png", Size: 1, StorageKey: "key", CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "photo.png", want: "photo.png"},
+		{name: "unix path", in: "../../etc/passwd", want: "passwd"},
+		{name: "windows path", in: `C:\Users\alice\report.pdf`, want: "report.pdf"},
+		{name: "surrounding space", in: "  notes.txt ", want: "notes.txt"},
+		{name: "empty", in: "", want: "attachment"},
+		{name: "dot", in: ".", want: "attachment"},
+		{name: "root", in: "/", want: "attachment"},
+		{name: "directory", in: "dir/", want: "dir"},
+		{name: "too long", in: strings.Repeat("a", maxAttachmentNameLength+10), want: strings.Repeat("a", maxAttachmentNameLength)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeFileName(tc.in); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFindUsableAttachments(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+
+	first := insertTestAttachment(t, repo, alice)
+	second := insertTestAttachment(t, repo, alice)
+	others := insertTestAttachment(t, repo, bob)
+
+	sent := insertTestAttachment(t, repo, alice)
+	msgID, err := repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: alice.ID, RecipientID: bob.ID, Message: "hi", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.LinkAttachments(ctx, &indto.AttachmentParams{IDs: []int64{sent}, UploaderID: alice.ID, MessageID: msgID}); err != nil {
+		t.Fatal(err)
+	}
+
+	many := []int64{}
+	for i := 0; i <= maxAttachmentsPerMessage; i++ {
+		many = append(many, insertTestAttachment(t, repo, alice))
+	}
+
+	cases := []struct {
+		name string
+		ids  []int64
+		want int
+		err  error
+	}{
+		{name: "own uploads", ids: []int64{second, first}, want: 2},
+		{name: "duplicated", ids: []int64{first, first, second}, want: 2},
+		{name: "max", ids: many[:maxAttachmentsPerMessage], want: maxAttachmentsPerMessage},
+		{name: "too many", ids: many, err: errs.ErrBadRequest},
+		{name: "upload of other user", ids: []int64{first, others}, err: errs.ErrBadRequest},
+		{name: "already sent", ids: []int64{sent}, err: errs.ErrBadRequest},
+		{name: "unknown", ids: []int64{first, 9999}, err: errs.ErrBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := findUsableAttachments(ctx, repo, alice.ID, tc.ids)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil || len(res) != tc.want {
+				t.Fatalf("expected %d attachments, got %d err %v", tc.want, len(res), err)
+			}
+		})
+	}
+}
+
+func TestFindAccessibleAttachment(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	carol := createTestUser(t, repo, "carol")
+
+	send := func(content string) (attachmentID int64, msgID int64) {
+		attachmentID = insertTestAttachment(t, repo, alice)
+
+		msgID, err := repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: alice.ID, RecipientID: bob.ID, Message: content, CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = repo.LinkAttachments(ctx, &indto.AttachmentParams{IDs: []int64{attachmentID}, UploaderID: alice.ID, MessageID: msgID}); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	unsent := insertTestAttachment(t, repo, alice)
+	sent, _ := send("hi")
+	deleted, deletedMsgID := send("oops")
+
+	now := time.Now()
+	if err := repo.DeleteChatHistory(ctx, &model.ChatHistory{ID: deletedMsgID, DeletedAt: &now}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name         string
+		attachmentID int64
+		userID       int64
+		err          error
+	}{
+		{name: "unsent by uploader", attachmentID: unsent, userID: alice.ID},
+		{name: "unsent by other user", attachmentID: unsent, userID: bob.ID, err: errs.ErrNotFound},
+		{name: "sent by sender", attachmentID: sent, userID: alice.ID},
+		{name: "sent by recipient", attachmentID: sent, userID: bob.ID},
+		{name: "sent by outsider", attachmentID: sent, userID: carol.ID, err: errs.ErrNotFound},
+		{name: "deleted message", attachmentID: deleted, userID: bob.ID, err: errs.ErrNotFound},
+		{name: "unknown", attachmentID: 9999, userID: alice.ID, err: errs.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := findAccessibleAttachment(ctx, repo, tc.attachmentID, tc.userID)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil || res.ID != tc.attachmentID {
+				t.Fatalf("expected attachment %d, got %+v err %v", tc.attachmentID, res, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/config"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/tokenutil"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// verifyCredential validate username and password pair against stored usermeta
+func verifyCredential(ctx context.Context, repo inrepo.Repository, cred *dto.AuthLoginPayload) (user *model.User, err error) {
+	user, err = repo.FindUser(ctx, &indto.UserParams{Username: cred.Username})
+	if err != nil {
+		return nil, err
+	} else if user == nil {
+		return nil, errs.ErrInvalidCred
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(cred.Password)); err != nil {
+		return nil, errs.ErrInvalidCred
+	}
+
+	return
+}
+
+// issueSession persist a new session for the user and sign its token
+func issueSession(ctx context.Context, repo inrepo.Repository, user *model.User) (res *dto.AuthSessionResponse, sessionID string, err error) {
+	conf := config.Get()
+
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	now := time.Now()
+	session := &model.UserSession{
+		ID:        hex.EncodeToString(b),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(conf.SessionConfig.TTL),
+		CreatedAt: now,
+	}
+
+	if err = repo.InsertUserSession(ctx, session); err != nil {
+		return
+	}
+
+	token, err := tokenutil.Sign([]byte(conf.SessionConfig.Secret), &tokenutil.Claims{
+		SessionID: session.ID,
+		UserID:    user.ID,
+		ExpiresAt: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return
+	}
+
+	res = &dto.AuthSessionResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		UserID:    user.ID,
+		Username:  user.Username,
+	}
+	sessionID = session.ID
+
+	return
+}
+
+// authenticateToken resolve a session token into its owner, revoked session is rejected even if the token is still valid
+func authenticateToken(ctx context.Context, repo inrepo.Repository, token string) (user *model.User, session *model.UserSession, err error) {
+	conf := config.Get()
+
+	claims, err := tokenutil.Verify([]byte(conf.SessionConfig.Secret), token)
+	if err != nil {
+		return nil, nil, errs.ErrInvalidToken
+	}
+
+	session, err = repo.FindActiveUserSession(ctx, &indto.UserSessionParams{ID: claims.SessionID})
+	if err != nil {
+		return
+	} else if session == nil || session.UserID != claims.UserID {
+		return nil, nil, errs.ErrInvalidToken
+	}
+
+	user, err = repo.FindUser(ctx, &indto.UserParams{ID: session.UserID})
+	if err != nil {
+		return
+	} else if user == nil {
+		return nil, nil, errs.ErrInvalidToken
+	}
+
+	return
+}
+
+// extractToken read session token from either bearer authorization header or `token` query param
+func extractToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return token
+		}
+	}
+
+	return r.URL.Query().Get("token")
+}
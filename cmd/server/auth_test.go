@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// dial open a socket which is not yet authenticated
+func (s *testServer) dial(t *testing.T, query string) *testClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(s.url+query, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testClient{t: t, conn: conn}
+}
+
+func TestSessionResume(t *testing.T) {
+	srv := newTestServer(t)
+	createTestUser(t, srv.repo, "alice")
+
+	login := srv.dial(t, "")
+	login.send(inconst.LiveChatAuthLoginEvent, map[string]any{"username": "alice", "password": "wrong"})
+	if msg := login.expectError(); msg != errs.ErrInvalidCred.Error() {
+		t.Fatalf("expected invalid credential, got %q", msg)
+	}
+
+	login.send(inconst.LiveChatAuthLoginEvent, map[string]any{"username": "alice", "password": testPassword})
+	session := decodeEvent[*dto.AuthSessionResponse](t, login.expect(inconst.LiveChatAuthAckEvent))
+	if session.Token == "" || session.Username != "alice" {
+		t.Fatalf("login should issue a session token, got %+v", session)
+	}
+
+	// token is accepted either as the first event or on the upgrade request
+	resumed := srv.dial(t, "")
+	resumed.send(inconst.LiveChatAuthTokenEvent, session.Token)
+	if ack := decodeEvent[*dto.AuthSessionResponse](t, resumed.expect(inconst.LiveChatAuthAckEvent)); ack.UserID != session.UserID {
+		t.Fatalf("expected session of alice, got %+v", ack)
+	}
+
+	if ack := decodeEvent[*dto.AuthSessionResponse](t, srv.dial(t, "?token="+session.Token).expect(inconst.LiveChatAuthAckEvent)); ack.UserID != session.UserID {
+		t.Fatalf("expected session of alice, got %+v", ack)
+	}
+
+	tampered := srv.dial(t, "")
+	tampered.send(inconst.LiveChatAuthTokenEvent, session.Token+"x")
+	if msg := tampered.expectError(); msg != errs.ErrInvalidToken.Error() {
+		t.Fatalf("expected invalid token, got %q", msg)
+	}
+
+	// logout revoke the session even though its token is not expired yet
+	login.send(inconst.LiveChatAuthLogoutEvent, nil)
+	for {
+		if _, _, err := login.conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("expected socket closed on logout, got %v", err)
+			}
+			break
+		}
+	}
+
+	revoked := srv.dial(t, "")
+	revoked.send(inconst.LiveChatAuthTokenEvent, session.Token)
+	if msg := revoked.expectError(); msg != errs.ErrInvalidToken.Error() {
+		t.Fatalf("expected invalid token, got %q", msg)
+	}
+}
+
+func TestExtractToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		url    string
+		token  string
+	}{
+		{name: "bearer header", header: "Bearer abc", url: "/", token: "abc"},
+		{name: "query param", url: "/?token=abc", token: "abc"},
+		{name: "header take precedence", header: "Bearer abc", url: "/?token=def", token: "abc"},
+		{name: "non bearer header fallback to query", header: "Basic abc", url: "/?token=def", token: "def"},
+		{name: "missing", url: "/"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+
+		if token := extractToken(r); token != tt.token {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.token, token)
+		}
+	}
+}
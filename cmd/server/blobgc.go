@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/component"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	blobGCInterval = time.Hour
+	// unreferenced blob used within the grace period is kept, covering upload which hasn't recorded its attachment yet
+	blobGCGracePeriod   = time.Hour
+	unsentAttachmentTTL = 24 * time.Hour
+)
+
+// blobCollector periodically drop uploads which were never sent and remove blobs no attachment refer to
+type blobCollector struct {
+	logger zerolog.Logger
+	repo   inrepo.Repository
+	store  component.BlobStore
+}
+
+func newBlobCollector(logger zerolog.Logger, repo inrepo.Repository, store component.BlobStore) *blobCollector {
+	return &blobCollector{logger: logger, repo: repo, store: store}
+}
+
+func (bc *blobCollector) Run() {
+	ticker := time.NewTicker(blobGCInterval)
+	defer ticker.Stop()
+
+	for {
+		bc.collect(time.Now())
+		<-ticker.C
+	}
+}
+
+func (bc *blobCollector) collect(now time.Time) {
+	ctx := bc.logger.WithContext(context.Background())
+
+	err := bc.repo.DeleteStaleAttachments(ctx, &indto.AttachmentParams{CreatedBefore: now.Add(-unsentAttachmentTTL)})
+	if err != nil {
+		bc.logger.Error().Err(err).Msg("failed to delete stale attachments")
+		return
+	}
+
+	usedBefore := now.Add(-blobGCGracePeriod)
+	blobs, err := bc.repo.FindUnreferencedBlobs(ctx, &indto.BlobParams{UsedBefore: usedBefore})
+	if err != nil {
+		bc.logger.Error().Err(err).Msg("failed to collect unreferenced blobs")
+		return
+	}
+
+	removed := 0
+	for _, blob := range blobs {
+		if ok, err := bc.remove(ctx, blob.StorageKey, usedBefore); err != nil {
+			bc.logger.Error().Err(err).Str("key", blob.StorageKey).Msg("failed to remove blob")
+		} else if ok {
+			removed++
+		}
+	}
+
+	if removed != 0 {
+		bc.logger.Info().Int("count", removed).Msg("unreferenced blobs removed")
+	}
+}
+
+// remove tombstone the blob once it is confirmed unreferenced then delete its content outside of any transaction, upload of the
+// same content meanwhile find the blob tombstoned and store its own copy. Failed removal keep the tombstone so it is retried on the next run
+func (bc *blobCollector) remove(ctx context.Context, key string, usedBefore time.Time) (removed bool, err error) {
+	blob, err := bc.repo.MarkBlobDeleted(ctx, &indto.BlobParams{StorageKey: key, UsedBefore: usedBefore, DeletedAt: time.Now()})
+	if err != nil || blob == nil {
+		return
+	}
+
+	if err = bc.store.Delete(ctx, blob.StorageKey); err != nil {
+		return
+	}
+
+	if err = bc.repo.DeleteBlob(ctx, &indto.BlobParams{StorageKey: blob.StorageKey}); err != nil {
+		return
+	}
+
+	return true, nil
+}
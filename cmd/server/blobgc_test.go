@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/rs/zerolog"
+)
+
+// memBlobStore keep blobs in memory, onDelete is called before a blob is removed and may fail the removal
+type memBlobStore struct {
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	onDelete func(key string) error
+}
+
+func newMemBlobStore() *memBlobStore {
+	return &memBlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *memBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = b
+	return nil
+}
+
+func (s *memBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.blobs[key]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *memBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.blobs[key]
+	return ok, nil
+}
+
+func (s *memBlobStore) Delete(ctx context.Context, key string) error {
+	if s.onDelete != nil {
+		if err := s.onDelete(key); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+func putTestBlob(t *testing.T, repo inrepo.Repository, store *memBlobStore, key string, lastUsedAt time.Time) {
+	t.Helper()
+
+	if _, err := repo.UpsertBlob(testContext(), &model.Blob{StorageKey: key, Size: 1, CreatedAt: lastUsedAt, LastUsedAt: lastUsedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Put(testContext(), key, bytes.NewReader([]byte(key)), int64(len(key)))
+}
+
+// trackedBlobs return keys of every blob record still kept
+func trackedBlobs(t *testing.T, repo inrepo.Repository) map[string]bool {
+	t.Helper()
+
+	blobs, err := repo.FindUnreferencedBlobs(testContext(), &indto.BlobParams{UsedBefore: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := map[string]bool{}
+	for _, b := range blobs {
+		res[b.StorageKey] = true
+	}
+
+	return res
+}
+
+func TestBlobCollector(t *testing.T) {
+	repo := newTestRepo(t)
+	store := newMemBlobStore()
+	uploader := createTestUser(t, repo, "alice")
+	now := time.Now()
+
+	putTestBlob(t, repo, store, "unused", now.Add(-2*blobGCGracePeriod))
+	putTestBlob(t, repo, store, "recent", now.Add(-blobGCGracePeriod/2))
+	putTestBlob(t, repo, store, "unsent", now.Add(-2*unsentAttachmentTTL))
+	putTestBlob(t, repo, store, "sent", now.Add(-2*unsentAttachmentTTL))
+	putTestBlob(t, repo, store, "broken", now.Add(-2*blobGCGracePeriod))
+
+	for key, messageID := range map[string]int64{"unsent": 0, "sent": 1} {
+		_, err := repo.InsertAttachment(testContext(), &model.Attachment{
+			MessageID: messageID, UploaderID: uploader.ID, FileName: key, ContentType: "text/plain", Size: 1, StorageKey: key,
+			CreatedAt: now.Add(-2 * unsentAttachmentTTL),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store.onDelete = func(key string) error {
+		if key == "broken" {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}
+
+	newBlobCollector(zerolog.Nop(), repo, store).collect(now)
+
+	for key, kept := range map[string]bool{"unused": false, "recent": true, "unsent": false, "sent": true, "broken": true} {
+		if exists, _ := store.Exists(testContext(), key); exists != kept {
+			t.Errorf("%s: expected content kept %v, got %v", key, kept, exists)
+		}
+	}
+
+	// referenced blob is never listed, the rest must keep its record whenever the content is kept
+	tracked := trackedBlobs(t, repo)
+	for key, want := range map[string]bool{"unused": false, "recent": true, "unsent": false, "broken": true} {
+		if tracked[key] != want {
+			t.Errorf("%s: expected record kept %v, got %v", key, want, tracked[key])
+		}
+	}
+
+	// failed removal is retried on the next run
+	store.onDelete = nil
+	newBlobCollector(zerolog.Nop(), repo, store).collect(now)
+	if exists, _ := store.Exists(testContext(), "broken"); exists || trackedBlobs(t, repo)["broken"] {
+		t.Error("broken: expected to be removed on retry")
+	}
+}
+
+// newTestFileHeader build multipart file as received by the upload handler
+func newTestFileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+
+	form, err := multipart.NewReader(body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	return form.File["file"][0]
+}
+
+// TestBlobCollectorConcurrentUpload upload the same content while the collector is removing it, removal must not block other
+// writes and the upload must never refer to content which is about to be removed
+func TestBlobCollectorConcurrentUpload(t *testing.T) {
+	repo := newTestRepo(t)
+	store := newMemBlobStore()
+	uploader := createTestUser(t, repo, "alice")
+	now := time.Now()
+
+	content := []byte("shared content")
+	sum := sha256.Sum256(content)
+	key := hex.EncodeToString(sum[:])
+	putTestBlob(t, repo, store, key, now.Add(-2*blobGCGracePeriod))
+
+	deleting, release := make(chan struct{}), make(chan struct{})
+	store.onDelete = func(key string) error {
+		close(deleting)
+		<-release
+		return nil
+	}
+
+	collected := make(chan struct{})
+	go func() {
+		newBlobCollector(zerolog.Nop(), repo, store).collect(now)
+		close(collected)
+	}()
+	<-deleting
+
+	written := make(chan error)
+	go func() {
+		_, err := repo.InsertChatHistory(testContext(), &model.ChatHistory{SenderID: uploader.ID, RecipientID: uploader.ID, Message: "hi", CreatedAt: time.Now()})
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		close(release)
+		t.Fatal("write should not wait for the content removal")
+	}
+
+	attachment, err := storeAttachment(testContext(), repo, store, uploader, newTestFileHeader(t, "a.txt", content))
+	if err != nil {
+		close(release)
+		t.Fatalf("upload failed: %v", err)
+	} else if attachment.StorageKey == key {
+		close(release)
+		t.Fatal("upload should not refer to the blob being removed")
+	}
+
+	close(release)
+	<-collected
+
+	if exists, _ := store.Exists(testContext(), key); exists || trackedBlobs(t, repo)[key] {
+		t.Fatal("collector should have removed the tombstoned blob")
+	}
+
+	store.onDelete = nil
+	newBlobCollector(zerolog.Nop(), repo, store).collect(time.Now())
+
+	blob, err := store.Get(testContext(), attachment.StorageKey)
+	if err != nil {
+		t.Fatalf("uploaded content must be kept: %v", err)
+	}
+	defer blob.Close()
+
+	if b, _ := io.ReadAll(blob); !bytes.Equal(b, content) {
+		t.Fatalf("unexpected content %q", b)
+	}
+
+	// once the removal finished the content is deduplicated under its hash again
+	again, err := storeAttachment(testContext(), repo, store, uploader, newTestFileHeader(t, "b.txt", content))
+	if err != nil || again.StorageKey != key {
+		t.Fatalf("expected upload stored under %s, got %+v err %v", key, again, err)
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	minConversationSize = 3 // two participants is a plain DM
+	maxConversationSize = 20
+)
+
+// conversationKey canonicalize participant set so the same users always resolve into the same conversation
+func conversationKey(userIDs []int64) string {
+	parts := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// findConversation fetch conversation along with its participants and ensure the user is one of them
+func findConversation(ctx context.Context, repo inrepo.Repository, conversationID int64, userID int64) (res *model.Conversation, err error) {
+	res, err = repo.FindConversation(ctx, &indto.ConversationParams{ID: conversationID})
+	if err != nil {
+		return nil, err
+	} else if res == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	res.Participants, err = repo.FindConversationParticipants(ctx, &indto.ConversationParams{ID: res.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	isParticipant := slices.ContainsFunc(res.Participants, func(p *model.ConversationParticipant) bool {
+		return p.UserID == userID
+	})
+	if !isParticipant {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
+
+// openConversation find the conversation between the sender and the listed users, creating it on first message
+func openConversation(ctx context.Context, repo inrepo.Repository, sender *model.User, usernames []string) (res *model.Conversation, err error) {
+	users, err := repo.FindUsers(ctx, &indto.UserParams{Usernames: usernames})
+	if err != nil {
+		return nil, err
+	}
+
+	// unknown username is rejected rather than silently dropped from the participant set
+	for _, username := range usernames {
+		known := slices.ContainsFunc(users, func(u *model.User) bool { return u.Username == username })
+		if !known {
+			return nil, errs.ErrNotFound
+		}
+	}
+
+	userIDs := []int64{sender.ID}
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+	}
+
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+	if len(userIDs) < minConversationSize || len(userIDs) > maxConversationSize {
+		return nil, errs.ErrBadRequest
+	}
+
+	key := conversationKey(userIDs)
+	res, err = repo.FindConversation(ctx, &indto.ConversationParams{ParticipantKey: key})
+	if err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		res = &model.Conversation{
+			ParticipantKey: key,
+			CreatedBy:      sender.ID,
+			CreatedAt:      time.Now(),
+		}
+
+		for _, id := range userIDs {
+			res.Participants = append(res.Participants, &model.ConversationParticipant{UserID: id})
+		}
+
+		res.ID, err = repo.InsertConversation(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return findConversation(ctx, repo, res.ID, sender.ID)
+}
+
+// fetchConversations list conversations of the user along with their participants
+func fetchConversations(ctx context.Context, repo inrepo.Repository, userID int64) (res []*indto.ConversationInfo, err error) {
+	conversations, err := repo.FindConversations(ctx, &indto.ConversationParams{UserID: userID})
+	if err != nil {
+		return
+	}
+
+	res = []*indto.ConversationInfo{}
+	if len(conversations) == 0 {
+		return
+	}
+
+	conversationIDs := []int64{}
+	for _, c := range conversations {
+		conversationIDs = append(conversationIDs, c.ID)
+	}
+
+	participants, err := repo.FindConversationParticipants(ctx, &indto.ConversationParams{ConversationIDs: conversationIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conversations {
+		info := &indto.ConversationInfo{ID: c.ID, Participants: []*indto.UserInfo{}, CreatedAt: c.CreatedAt}
+		for _, p := range participants {
+			if p.ConversationID == c.ID {
+				info.Participants = append(info.Participants, &indto.UserInfo{ID: p.UserID, Username: p.Username})
+			}
+		}
+
+		res = append(res, info)
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) sendConversationError(err error) {
+	errMsg := "failed to fetch conversation"
+	switch {
+	case errors.Is(err, errs.ErrNotFound), errors.Is(err, errs.ErrForbidden):
+		errMsg = "conversation or participant doesnt exists"
+	case errors.Is(err, errs.ErrBadRequest):
+		errMsg = "group conversation require 2 to 19 other participants"
+	default:
+		lc.logger.Error().Err(err).Msg("failed to fetch conversation")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      errMsg,
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleGroupMessage(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid group message payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.ChatGroupPayload](data)
+
+	var conversation *model.Conversation
+	var err error
+	if payload.ConversationID != 0 {
+		conversation, err = findConversation(lc.ctx, lc.repo, payload.ConversationID, lc.UserID)
+	} else {
+		conversation, err = openConversation(lc.ctx, lc.repo, lc.user(), payload.Usernames)
+	}
+
+	if err != nil {
+		lc.sendConversationError(err)
+		return
+	}
+
+	_, err = postMessage(lc.ctx, lc.repo, lc.hub, &outgoingMessage{
+		Sender:        lc.user(),
+		Conversation:  conversation,
+		Envelope:      payload.MessageEnvelope,
+		AttachmentIDs: payload.AttachmentIDs,
+	})
+	if err != nil {
+		lc.sendPostError(err)
+		return
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) sendConversationLog(payload *dto.ChatLogPayload) {
+	conversation, err := findConversation(lc.ctx, lc.repo, payload.ConversationID, lc.UserID)
+	if err != nil {
+		lc.sendConversationError(err)
+		return
+	}
+
+	lc.sendChatLog(&indto.ChatHistoryParams{ConversationID: conversation.ID}, payload)
+}
+
+func (lc *LiveChatSocketMiddleware) sendConversationList() {
+	res, err := fetchConversations(lc.ctx, lc.repo, lc.UserID)
+	if err != nil {
+		lc.sendConversationError(err)
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatGroupListEvent,
+		Data:      res,
+	}
+}
+
+func HandleListConversations(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		res, err := fetchConversations(ctx, params.Repo, sessionUser(c).ID)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch conversations")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
+
+func HandleConversationHistory(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		conversationID, err := paramID(c, "conversation_id")
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		// non participant shouldn't learn whether the conversation exists
+		conversation, err := findConversation(ctx, params.Repo, conversationID, sessionUser(c).ID)
+		if errors.Is(err, errs.ErrForbidden) {
+			err = errs.ErrNotFound
+		}
+
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		payload := &dto.ChatLogPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+		normalizeChatLogLimit(payload)
+
+		res, err := fetchChatLog(ctx, params.Repo, &indto.ChatHistoryParams{ConversationID: conversation.ID}, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to get message log")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
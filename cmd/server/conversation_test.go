@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestConversationKey(t *testing.T) {
+	cases := []struct {
+		name    string
+		userIDs []int64
+		want    string
+	}{
+		{name: "empty", userIDs: nil, want: ""},
+		{name: "single", userIDs: []int64{7}, want: "7"},
+		{name: "many", userIDs: []int64{1, 2, 30}, want: "1,2,30"},
+		// digits are kept whole so 1,23 and 12,3 never collide
+		{name: "multi digit", userIDs: []int64{1, 23}, want: "1,23"},
+		{name: "multi digit swapped", userIDs: []int64{12, 3}, want: "12,3"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := conversationKey(tc.userIDs); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOpenConversation(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	carol := createTestUser(t, repo, "carol")
+	dave := createTestUser(t, repo, "dave")
+
+	first, err := openConversation(ctx, repo, alice, []string{"bob", "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantKey := conversationKey([]int64{alice.ID, bob.ID, carol.ID})
+	if first.ParticipantKey != wantKey || len(first.Participants) != 3 {
+		t.Fatalf("expected key %q with 3 participants, got %q with %d", wantKey, first.ParticipantKey, len(first.Participants))
+	}
+
+	// the same participant set resolve into the same conversation whoever open it and however it is listed
+	same := []struct {
+		name      string
+		sender    *model.User
+		usernames []string
+	}{
+		{name: "reversed", sender: alice, usernames: []string{"carol", "bob"}},
+		{name: "other sender", sender: carol, usernames: []string{"alice", "bob"}},
+		{name: "sender listed", sender: bob, usernames: []string{"bob", "alice", "carol"}},
+		{name: "duplicated", sender: alice, usernames: []string{"bob", "carol", "bob"}},
+	}
+
+	for _, tc := range same {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := openConversation(ctx, repo, tc.sender, tc.usernames)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.ID != first.ID {
+				t.Fatalf("expected conversation %d, got %d", first.ID, res.ID)
+			}
+		})
+	}
+
+	other, err := openConversation(ctx, repo, alice, []string{"bob", "dave"})
+	if err != nil {
+		t.Fatal(err)
+	} else if other.ID == first.ID {
+		t.Fatal("different participant set should open a new conversation")
+	}
+
+	tooMany := []string{}
+	for i := 0; i < maxConversationSize; i++ {
+		name := fmt.Sprintf("user%02d", i)
+		createTestUser(t, repo, name)
+		tooMany = append(tooMany, name)
+	}
+
+	invalid := []struct {
+		name      string
+		usernames []string
+		want      error
+	}{
+		{name: "no participant", usernames: nil, want: errs.ErrBadRequest},
+		{name: "plain dm", usernames: []string{"bob"}, want: errs.ErrBadRequest},
+		{name: "dm with sender listed", usernames: []string{"alice", "bob", "bob"}, want: errs.ErrBadRequest},
+		{name: "too many", usernames: tooMany, want: errs.ErrBadRequest},
+		{name: "max size", usernames: tooMany[:maxConversationSize-1], want: nil},
+		{name: "unknown user", usernames: []string{"bob", "mallory"}, want: errs.ErrNotFound},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := openConversation(ctx, repo, alice, tc.usernames)
+			if tc.want == nil && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			} else if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	t.Run("find", func(t *testing.T) {
+		if _, err := findConversation(ctx, repo, first.ID, carol.ID); err != nil {
+			t.Fatalf("participant should find the conversation: %v", err)
+		}
+
+		if _, err := findConversation(ctx, repo, first.ID, dave.ID); !errors.Is(err, errs.ErrForbidden) {
+			t.Fatalf("expected forbidden for non participant, got %v", err)
+		}
+
+		if _, err := findConversation(ctx, repo, first.ID+1000, alice.ID); !errors.Is(err, errs.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		res, err := fetchConversations(ctx, repo, dave.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 1 || res[0].ID != other.ID || len(res[0].Participants) != 3 {
+			t.Fatalf("expected only conversation %d with 3 participants, got %+v", other.ID, res)
+		}
+	})
+}
+
+func TestGroupMessage(t *testing.T) {
+	srv := newTestServer(t)
+
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+	dave := srv.connect(t, "dave")
+
+	alice.send(inconst.LiveChatSendGroupMsgEvent, map[string]any{"usernames": []string{"bob", "carol"}, "content": "hi all"})
+
+	sent := alice.expectIncoming("hi all")
+	if sent.ConversationID == 0 || !sent.IsDM {
+		t.Fatalf("expected group message tagged with its conversation, got %+v", sent)
+	}
+
+	for _, c := range []*testClient{bob, carol} {
+		if msg := c.expectIncoming("hi all"); msg.ID != sent.ID || msg.ConversationID != sent.ConversationID {
+			t.Fatalf("expected message %d of conversation %d, got %+v", sent.ID, sent.ConversationID, msg)
+		}
+	}
+
+	// reply through the conversation id reach the same participants
+	carol.send(inconst.LiveChatSendGroupMsgEvent, map[string]any{"conversation_id": sent.ConversationID, "content": "hello"})
+	for _, c := range []*testClient{alice, bob} {
+		if msg := c.expectIncoming("hello"); msg.ConversationID != sent.ConversationID {
+			t.Fatalf("expected reply in conversation %d, got %d", sent.ConversationID, msg.ConversationID)
+		}
+	}
+
+	bob.send(inconst.LiveChatGroupLogEvent, map[string]any{"conversation_id": sent.ConversationID})
+	log := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatMsgLogEvent))
+	if len(log.Messages) != 2 {
+		t.Fatalf("expected 2 messages in the log, got %d", len(log.Messages))
+	}
+
+	bob.send(inconst.LiveChatListGroupEvent, nil)
+	list := decodeEvent[[]*indto.ConversationInfo](t, bob.expect(inconst.LiveChatGroupListEvent))
+	if len(list) != 1 || list[0].ID != sent.ConversationID {
+		t.Fatalf("expected conversation %d to be listed, got %+v", sent.ConversationID, list)
+	}
+
+	// outsider can neither post into nor read the conversation
+	dave.send(inconst.LiveChatSendGroupMsgEvent, map[string]any{"conversation_id": sent.ConversationID, "content": "sneaky"})
+	if msg := dave.expectError(); msg != "conversation or participant doesnt exists" {
+		t.Fatalf("unexpected error: %q", msg)
+	}
+
+	dave.send(inconst.LiveChatGroupLogEvent, map[string]any{"conversation_id": sent.ConversationID})
+	if msg := dave.expectError(); msg != "conversation or participant doesnt exists" {
+		t.Fatalf("unexpected error: %q", msg)
+	}
+
+	dave.send(inconst.LiveChatSendGroupMsgEvent, map[string]any{"usernames": []string{"alice"}, "content": "dm"})
+	if msg := dave.expectError(); msg != "group conversation require 2 to 19 other participants" {
+		t.Fatalf("unexpected error: %q", msg)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+const (
+	pendingFlushPageSize     = 100
+	pendingFlushPollInterval = 20 * time.Millisecond
+)
+
+// directDedupe keep direct message from reaching the connection twice, once flushed as pending and once delivered live
+// by the hub while the connection were already registered but its pending messages weren't marked as delivered yet
+type directDedupe struct {
+	flushing bool           // live messages are recorded until the flush is done
+	live     map[int64]bool // delivered by the hub during the flush
+	flushed  map[int64]bool // delivered by the flush, bounded by the pending messages at login
+
+	mutex sync.Mutex
+}
+
+func newDirectDedupe() *directDedupe {
+	return &directDedupe{
+		flushing: true,
+		live:     map[int64]bool{},
+		flushed:  map[int64]bool{},
+	}
+}
+
+// claimLive tell whether the hub should deliver the message
+func (d *directDedupe) claimLive(messageID int64) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.flushed[messageID] {
+		return false
+	}
+
+	if d.flushing {
+		d.live[messageID] = true
+	}
+
+	return true
+}
+
+// claimFlushed tell whether the flush should deliver the message
+func (d *directDedupe) claimFlushed(messageID int64) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.live[messageID] {
+		return false
+	}
+
+	d.flushed[messageID] = true
+	return true
+}
+
+func (d *directDedupe) finishFlush() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.flushing = false
+	d.live = nil
+}
+
+// flushPendingMessages deliver direct messages queued while the user were offline page by page, then notify their senders.
+// The connection is registered beforehand so nothing sent meanwhile is missed, hence the dedupe
+func (lc *LiveChatSocketMiddleware) flushPendingMessages() {
+	defer lc.dedupe.finishFlush()
+
+	for {
+		if !lc.awaitFlushRoom() {
+			lc.logger.Warn().Msg("outbound buffer isnt drained, pending messages are left for the next login")
+			return
+		}
+
+		n, err := lc.flushPendingPage()
+		if err != nil || n < pendingFlushPageSize {
+			return
+		}
+	}
+}
+
+// awaitFlushRoom wait until the outbound buffer could take a page while leaving as much room for live delivery,
+// the hub evict connection whose buffer is full
+func (lc *LiveChatSocketMiddleware) awaitFlushRoom() bool {
+	deadline := time.Now().Add(writeWait)
+	for len(lc.in) > cap(lc.in)-2*pendingFlushPageSize {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(pendingFlushPollInterval)
+	}
+
+	return true
+}
+
+// flushPendingPage deliver the oldest pending messages and mark them delivered so the next page start after them
+func (lc *LiveChatSocketMiddleware) flushPendingPage() (n int, err error) {
+	msg, err := lc.repo.FindUndeliveredMessages(lc.ctx, &indto.MessageDeliveryParams{RecipientID: lc.UserID, Limit: pendingFlushPageSize})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch undelivered message")
+		return
+	} else if len(msg) == 0 {
+		return
+	}
+
+	incoming := toIncomingMessages(msg)
+	if err = loadAttachments(lc.ctx, lc.repo, incoming); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch attachments")
+		return
+	}
+
+	msgIDs := []int64{}
+	for _, m := range incoming {
+		msgIDs = append(msgIDs, m.ID)
+		if !lc.dedupe.claimFlushed(m.ID) {
+			continue
+		}
+
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatIncomingMsgEvent,
+			Data:      m,
+		}
+	}
+
+	err = lc.repo.MarkMessageDelivered(lc.ctx, &indto.MessageDeliveryParams{RecipientID: lc.UserID, MessageIDs: msgIDs})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to mark message as delivered")
+		return
+	}
+
+	for _, m := range msg {
+		lc.hub.SendToUser(m.SenderID, dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatDirectStatusEvent,
+			Data: &indto.DeliveryStatus{
+				MessageID:      m.ID,
+				ConversationID: m.ConversationID,
+				RecipientID:    lc.UserID,
+				Status:         inconst.DeliveryStatusDelivered,
+			},
+		})
+	}
+
+	return len(msg), nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+)
+
+// pendingGateRepo hold pending lookup of the recipient until released, live delivery isnt flagged until the lookup is done
+type pendingGateRepo struct {
+	inrepo.Repository
+	recipientID int64
+	release     chan struct{}
+	fetched     chan struct{}
+}
+
+func (r *pendingGateRepo) FindUndeliveredMessages(ctx context.Context, params *indto.MessageDeliveryParams) ([]*model.ChatHistory, error) {
+	if params.RecipientID != r.recipientID {
+		return r.Repository.FindUndeliveredMessages(ctx, params)
+	}
+
+	<-r.release
+	defer close(r.fetched)
+	return r.Repository.FindUndeliveredMessages(ctx, params)
+}
+
+func (r *pendingGateRepo) MarkMessageDelivered(ctx context.Context, params *indto.MessageDeliveryParams) error {
+	if params.RecipientID == r.recipientID {
+		<-r.fetched
+	}
+	return r.Repository.MarkMessageDelivered(ctx, params)
+}
+
+func TestDirectMessageNotDuplicatedOnLogin(t *testing.T) {
+	repo := &pendingGateRepo{Repository: newTestRepo(t), release: make(chan struct{}), fetched: make(chan struct{})}
+	srv := newTestServerWithRepo(t, repo)
+
+	alice := srv.connect(t, "alice")
+	repo.recipientID = createTestUser(t, repo, "bob").ID
+
+	// bob is registered while his pending messages are still being fetched
+	bob := srv.connect(t, "bob")
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "first"})
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "first" {
+		t.Fatalf("expected first, got %q", msg.Content)
+	}
+
+	// the message is still pending when fetched, flush must not send it again
+	close(repo.release)
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "second"})
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "second" {
+		t.Fatalf("expected second, got %q", msg.Content)
+	}
+}
+
+func TestPendingMessagesFlushedOnLogin(t *testing.T) {
+	srv := newTestServer(t)
+
+	alice := srv.connect(t, "alice")
+	createTestUser(t, srv.repo, "bob")
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "offline"})
+	alice.expect(inconst.LiveChatDirectStatusEvent)
+
+	bob := srv.connect(t, "bob")
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "offline" {
+		t.Fatalf("expected offline, got %q", msg.Content)
+	}
+
+	// the sender is told once the queued message reach the recipient
+	for {
+		status := decodeEvent[*indto.DeliveryStatus](t, alice.expect(inconst.LiveChatDirectStatusEvent))
+		if status.Status == inconst.DeliveryStatusDelivered {
+			break
+		}
+	}
+}
+
+// pageRecordingRepo record size of every pending lookup made for the recipient
+type pageRecordingRepo struct {
+	inrepo.Repository
+	recipientID int64
+
+	mutex sync.Mutex
+	pages []int
+}
+
+func (r *pageRecordingRepo) FindUndeliveredMessages(ctx context.Context, params *indto.MessageDeliveryParams) ([]*model.ChatHistory, error) {
+	res, err := r.Repository.FindUndeliveredMessages(ctx, params)
+	if params.RecipientID == r.recipientID {
+		r.mutex.Lock()
+		r.pages = append(r.pages, len(res))
+		r.mutex.Unlock()
+	}
+	return res, err
+}
+
+func TestPendingMessagesFlushedInPages(t *testing.T) {
+	repo := &pageRecordingRepo{Repository: newTestRepo(t)}
+	srv := newTestServerWithRepo(t, repo)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	repo.recipientID = bob.ID
+
+	total := 2*pendingFlushPageSize + 5
+	for i := 0; i < total; i++ {
+		now := time.Now()
+		msgID, err := repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: alice.ID, RecipientID: bob.ID, Message: fmt.Sprint(i), CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = repo.InsertMessageDelivery(ctx, &model.MessageDelivery{MessageID: msgID, RecipientID: bob.ID, CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := srv.connect(t, "bob")
+	for i := 0; i < total; i++ {
+		if msg := decodeEvent[*indto.IncomingMessage](t, client.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != fmt.Sprint(i) {
+			t.Fatalf("expected message %d in order, got %q", i, msg.Content)
+		}
+	}
+
+	// every page is marked delivered before the next is fetched, the short last page end the flush
+	client.expect(inconst.LiveChatUnreadEvent)
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+	if fmt.Sprint(repo.pages) != fmt.Sprint([]int{pendingFlushPageSize, pendingFlushPageSize, 5}) {
+		t.Fatalf("unexpected pages %v", repo.pages)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	defaultDirectoryLimit = 20
+	maxDirectoryLimit     = 100
+)
+
+// fetchRoomDirectory list discoverable rooms matching the query, one extra row is queried to determine whether more page exists
+func fetchRoomDirectory(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, payload *dto.RoomDirectoryPayload) (res *indto.RoomDirectory, err error) {
+	if payload.Limit == 0 {
+		payload.Limit = defaultDirectoryLimit
+	} else if payload.Limit > maxDirectoryLimit {
+		payload.Limit = maxDirectoryLimit
+	}
+
+	rooms, err := repo.FindRoomDirectory(ctx, &indto.RoomDirectoryParams{
+		Query:        payload.Query,
+		Visibilities: []string{inconst.RoomVisibilityPublic, inconst.RoomVisibilityInviteOnly},
+		Limit:        payload.Limit + 1,
+		Offset:       payload.Offset,
+	})
+	if err != nil {
+		return
+	}
+
+	res = &indto.RoomDirectory{Rooms: []*indto.RoomDirectoryEntry{}}
+	if uint64(len(rooms)) > payload.Limit {
+		rooms = rooms[:payload.Limit]
+		res.HasMore, res.NextOffset = true, payload.Offset+payload.Limit
+	}
+
+	for _, r := range rooms {
+		res.Rooms = append(res.Rooms, &indto.RoomDirectoryEntry{
+			RoomInfo:       toRoomInfos([]*model.ChatRoom{&r.ChatRoom})[0],
+			MemberCount:    r.MemberCount,
+			OnlineCount:    hub.OnlineCount(r.ID),
+			LastActivityAt: r.LastActivityAt,
+		})
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) sendRoomDirectory(event *dto.LiveChatSocketEvent) {
+	payload := &dto.RoomDirectoryPayload{}
+	switch v := event.Data.(type) {
+	case string:
+		payload.Query = v
+	case map[string]any:
+		payload = structutil.MapToStruct[*dto.RoomDirectoryPayload](v)
+	}
+
+	res, err := fetchRoomDirectory(lc.ctx, lc.repo, lc.hub, payload)
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch room directory")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch room directory",
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatDirectoryEvent,
+		Data:      res,
+	}
+}
+
+// HandleRoomDirectory search discoverable rooms by name or topic with `q`, paginated by `limit` and `offset`
+func HandleRoomDirectory(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		payload := &dto.RoomDirectoryPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		res, err := fetchRoomDirectory(ctx, params.Repo, params.Hub, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch room directory")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestFetchRoomDirectory(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := testContext()
+
+	users := map[string]int64{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		users[name] = createTestUser(t, srv.repo, name).ID
+	}
+
+	members := func(names ...string) map[int64]string {
+		res := map[int64]string{}
+		for _, name := range names {
+			res[users[name]] = inconst.RoomRoleMember
+		}
+		return res
+	}
+
+	alpha := createTestRoom(t, srv.repo, "alpha", members("alice", "bob", "carol"))
+	beta := createTestRoom(t, srv.repo, "beta", members("alice", "bob"))
+	createTestRoom(t, srv.repo, "a_b", members("alice"))
+
+	beta.Topic = "all about golang"
+	if err := srv.repo.UpdateRoom(ctx, beta); err != nil {
+		t.Fatal(err)
+	}
+
+	// private room is never listed however crowded it is
+	secret := createTestRoom(t, srv.repo, "alpha secret", members("alice", "bob", "carol", "dave"))
+	secret.Visibility = inconst.RoomVisibilityPrivate
+	if err := srv.repo.UpdateRoom(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	bob := srv.connect(t, "bob")
+	bob.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": beta.ID, "content": "hi"})
+	bob.expectIncoming("hi")
+
+	tests := []struct {
+		name       string
+		payload    *dto.RoomDirectoryPayload
+		rooms      string
+		hasMore    bool
+		nextOffset uint64
+	}{
+		{name: "ordered by member count", payload: &dto.RoomDirectoryPayload{}, rooms: "[alpha beta a_b]"},
+		{name: "first page", payload: &dto.RoomDirectoryPayload{Limit: 2}, rooms: "[alpha beta]", hasMore: true, nextOffset: 2},
+		{name: "last page", payload: &dto.RoomDirectoryPayload{Limit: 2, Offset: 2}, rooms: "[a_b]"},
+		{name: "match topic", payload: &dto.RoomDirectoryPayload{Query: "golang"}, rooms: "[beta]"},
+		{name: "wildcard is literal", payload: &dto.RoomDirectoryPayload{Query: "_"}, rooms: "[a_b]"},
+		{name: "no match", payload: &dto.RoomDirectoryPayload{Query: "secret"}, rooms: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := fetchRoomDirectory(ctx, srv.repo, srv.hub, tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for _, r := range res.Rooms {
+				names = append(names, r.RoomName)
+			}
+
+			if fmt.Sprint(names) != tt.rooms || res.HasMore != tt.hasMore || res.NextOffset != tt.nextOffset {
+				t.Fatalf("expected %s has more %v next %d, got %v has more %v next %d", tt.rooms, tt.hasMore, tt.nextOffset, names, res.HasMore, res.NextOffset)
+			}
+		})
+	}
+
+	res, err := fetchRoomDirectory(ctx, srv.repo, srv.hub, &dto.RoomDirectoryPayload{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := res.Rooms[0], res.Rooms[1]
+	if first.ID != alpha.ID || first.MemberCount != 3 || first.OnlineCount != 1 || first.LastActivityAt != nil {
+		t.Fatalf("unexpected alpha entry %+v", first)
+	}
+
+	if second.MemberCount != 2 || second.OnlineCount != 1 || second.LastActivityAt == nil {
+		t.Fatalf("unexpected beta entry %+v", second)
+	}
+}
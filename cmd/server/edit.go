@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+// findOwnedMessage fetch a live message which could be modified by the user,
+// allowModerator let room moderators act on message of other members
+func findOwnedMessage(ctx context.Context, repo inrepo.Repository, messageID int64, userID int64, allowModerator bool) (msg *model.ChatHistory, err error) {
+	msg, err = findAccessibleMessage(ctx, repo, messageID, userID)
+	if errors.Is(err, errs.ErrForbidden) {
+		return nil, errs.ErrNotFound // dont leak message outside user conversation
+	} else if err != nil {
+		return nil, err
+	} else if msg.DeletedAt != nil {
+		return nil, errs.ErrNotFound
+	}
+
+	if msg.SenderID == userID {
+		return
+	} else if !allowModerator || msg.RoomID == 0 {
+		return nil, errs.ErrForbidden
+	}
+
+	participant, err := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: msg.RoomID, UserID: userID})
+	if err != nil {
+		return nil, err
+	} else if !isRoomModerator(participant) {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) handleMessageEdit(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid edit payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.MessageEditPayload](data)
+
+	if strings.TrimSpace(payload.Content) == "" {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "message content cannot be empty",
+		}
+		return
+	} else if utf8.RuneCountInString(payload.Content) > maxMessageLength {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "message content is too long",
+		}
+		return
+	}
+
+	msg, err := findOwnedMessage(lc.ctx, lc.repo, payload.MessageID, lc.UserID, false)
+	if err != nil {
+		lc.sendMessageModifyError(err)
+		return
+	}
+
+	// content of a card is only its fallback text, the card itself is resent rather than edited
+	if msg.MessageType == inconst.MessageTypeCard {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "card message cannot be edited",
+		}
+		return
+	}
+
+	now := time.Now()
+	msg.Message, msg.EditedAt = payload.Content, &now
+
+	if err = lc.repo.UpdateChatHistory(lc.ctx, msg); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to edit message")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to edit message",
+		}
+		return
+	}
+
+	// edit only touch the content, keep attachments so client could replace the message as a whole
+	edited := toIncomingMessages([]*model.ChatHistory{msg})[0]
+	if err = loadAttachments(lc.ctx, lc.repo, []*indto.IncomingMessage{edited}); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch attachments")
+	}
+
+	err = publishMessageEvent(lc.ctx, lc.repo, lc.hub, msg, dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatEditedMsgEvent,
+		Data:      edited,
+	})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to publish message event")
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleMessageDelete(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid delete payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.MessageDeletePayload](data)
+
+	msg, err := findOwnedMessage(lc.ctx, lc.repo, payload.MessageID, lc.UserID, true)
+	if err != nil {
+		lc.sendMessageModifyError(err)
+		return
+	}
+
+	now := time.Now()
+	msg.Message, msg.MessageMeta, msg.DeletedAt = "", "", &now
+
+	if err = lc.repo.DeleteChatHistory(lc.ctx, msg); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to delete message")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to delete message",
+		}
+		return
+	}
+
+	err = publishMessageEvent(lc.ctx, lc.repo, lc.hub, msg, dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatDeletedMsgEvent,
+		Data:      toIncomingMessages([]*model.ChatHistory{msg})[0],
+	})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to publish message event")
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) sendMessageModifyError(err error) {
+	errMsg := "failed to fetch message"
+	if errors.Is(err, errs.ErrNotFound) {
+		errMsg = "message doesnt exists"
+	} else if errors.Is(err, errs.ErrForbidden) {
+		errMsg = "not allowed to modify the message"
+	} else {
+		lc.logger.Error().Err(err).Msg("failed to fetch message")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      errMsg,
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestFindOwnedMessage(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	carol := createTestUser(t, repo, "carol")
+	dave := createTestUser(t, repo, "dave")
+
+	room := createTestRoom(t, repo, "general", map[int64]string{
+		alice.ID: inconst.RoomRoleMember,
+		bob.ID:   inconst.RoomRoleModerator,
+		carol.ID: inconst.RoomRoleMember,
+	})
+
+	insert := func(msg *model.ChatHistory) int64 {
+		msg.MessageType, msg.CreatedAt = inconst.MessageTypeText, time.Now()
+		id, err := repo.InsertChatHistory(ctx, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	roomMsg := insert(&model.ChatHistory{RoomID: room.ID, SenderID: alice.ID, Message: "hi"})
+	directMsg := insert(&model.ChatHistory{SenderID: alice.ID, RecipientID: carol.ID, Message: "psst"})
+	deletedMsg := insert(&model.ChatHistory{RoomID: room.ID, SenderID: alice.ID, Message: "oops"})
+
+	now := time.Now()
+	if err := repo.DeleteChatHistory(ctx, &model.ChatHistory{ID: deletedMsg, DeletedAt: &now}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		messageID      int64
+		userID         int64
+		allowModerator bool
+		err            error
+	}{
+		{name: "sender", messageID: roomMsg, userID: alice.ID},
+		{name: "sender of direct message", messageID: directMsg, userID: alice.ID, allowModerator: true},
+		{name: "other member", messageID: roomMsg, userID: carol.ID, allowModerator: true, err: errs.ErrForbidden},
+		{name: "moderator", messageID: roomMsg, userID: bob.ID, allowModerator: true},
+		{name: "moderator editing", messageID: roomMsg, userID: bob.ID, err: errs.ErrForbidden},
+		{name: "direct message recipient", messageID: directMsg, userID: carol.ID, allowModerator: true, err: errs.ErrForbidden},
+		{name: "outsider of the room", messageID: roomMsg, userID: dave.ID, allowModerator: true, err: errs.ErrNotFound},
+		{name: "outsider of direct message", messageID: directMsg, userID: bob.ID, allowModerator: true, err: errs.ErrNotFound},
+		{name: "deleted message", messageID: deletedMsg, userID: alice.ID, err: errs.ErrNotFound},
+		{name: "unknown message", messageID: 1000, userID: alice.ID, err: errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := findOwnedMessage(ctx, repo, tt.messageID, tt.userID, tt.allowModerator)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if err == nil && msg.ID != tt.messageID {
+				t.Fatalf("expected message %d, got %d", tt.messageID, msg.ID)
+			}
+		})
+	}
+}
+
+func TestEditAndDeleteMessage(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "helo"})
+	sent := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent))
+
+	bob.send(inconst.LiveChatEditMsgEvent, map[string]any{"message_id": sent.ID, "content": "hijacked"})
+	if msg := bob.expectError(); msg != "not allowed to modify the message" {
+		t.Fatalf("expected not allowed, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatEditMsgEvent, map[string]any{"message_id": sent.ID, "content": "  "})
+	if msg := alice.expectError(); msg != "message content cannot be empty" {
+		t.Fatalf("expected empty content error, got %q", msg)
+	}
+
+	// both side see the edit
+	alice.send(inconst.LiveChatEditMsgEvent, map[string]any{"message_id": sent.ID, "content": "hello"})
+	for _, c := range []*testClient{alice, bob} {
+		edited := decodeEvent[*indto.IncomingMessage](t, c.expect(inconst.LiveChatEditedMsgEvent))
+		if edited.ID != sent.ID || edited.Content != "hello" || edited.EditedAt == nil {
+			t.Fatalf("unexpected edit %+v", edited)
+		}
+	}
+
+	alice.send(inconst.LiveChatDeleteMsgEvent, map[string]any{"message_id": sent.ID})
+	deleted := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatDeletedMsgEvent))
+	if deleted.ID != sent.ID || deleted.Content != "" || deleted.DeletedAt == nil {
+		t.Fatalf("deleted message should be blanked, got %+v", deleted)
+	}
+
+	// deleted message stay in history as a tombstone and can not be edited again
+	bob.send(inconst.LiveChatDirectLogEvent, "alice")
+	history := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatMsgLogEvent))
+	if len(history.Messages) != 1 || history.Messages[0].DeletedAt == nil || history.Messages[0].Content != "" {
+		t.Fatalf("expected tombstone in history, got %+v", history.Messages)
+	}
+
+	alice.send(inconst.LiveChatEditMsgEvent, map[string]any{"message_id": sent.ID, "content": "back"})
+	if msg := alice.expectError(); msg != "message doesnt exists" {
+		t.Fatalf("expected message doesnt exists, got %q", msg)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+const (
+	maxMessageLength    = 8000
+	maxCardTitleLength  = 256
+	maxCardTextLength   = 4000
+	maxCardFields       = 25
+	maxCardFieldLength  = 1024
+	maxCardButtons      = 5
+	maxCardButtonLength = 80
+	maxCardURLLength    = 2048
+)
+
+var codeLanguagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,31}$`)
+
+// messageMeta hold type specific part of the envelope, stored as json next to the message content
+type messageMeta struct {
+	Language string           `json:"language,omitempty"`
+	Card     *dto.MessageCard `json:"card,omitempty"`
+}
+
+func invalidMessage(reason string) error {
+	return fmt.Errorf("%w: %s", errs.ErrInvalidMsg, reason)
+}
+
+// validateEnvelope check the envelope sent by client, missing type is treated as plain text
+// and card without content use its title as the plain text fallback
+func validateEnvelope(env *dto.MessageEnvelope, hasAttachment bool) (err error) {
+	if env.Type == "" {
+		env.Type = inconst.MessageTypeText
+	}
+
+	if utf8.RuneCountInString(env.Content) > maxMessageLength {
+		return invalidMessage(fmt.Sprintf("content exceed %d characters", maxMessageLength))
+	}
+
+	if env.Type != inconst.MessageTypeCode && env.Language != "" {
+		return invalidMessage("language is only allowed for code")
+	}
+
+	if env.Type != inconst.MessageTypeCard && env.Card != nil {
+		return invalidMessage("card is only allowed for card message")
+	}
+
+	isBlank := strings.TrimSpace(env.Content) == ""
+
+	switch env.Type {
+	case inconst.MessageTypeText, inconst.MessageTypeMarkdown:
+		if isBlank && !hasAttachment {
+			return invalidMessage("content cannot be empty")
+		}
+	case inconst.MessageTypeCode:
+		if isBlank {
+			return invalidMessage("code cannot be empty")
+		}
+
+		env.Language = strings.ToLower(env.Language)
+		if env.Language != "" && !codeLanguagePattern.MatchString(env.Language) {
+			return invalidMessage("invalid code language")
+		}
+	case inconst.MessageTypeCard:
+		if err = validateCard(env.Card); err != nil {
+			return
+		}
+
+		if isBlank {
+			env.Content = env.Card.Title
+		}
+	case inconst.MessageTypeSystem:
+		return invalidMessage("system message is reserved for the server")
+	default:
+		return invalidMessage(fmt.Sprintf("unknown message type %q", env.Type))
+	}
+
+	return
+}
+
+func validateCard(card *dto.MessageCard) (err error) {
+	if card == nil {
+		return invalidMessage("card is required")
+	}
+
+	if strings.TrimSpace(card.Title) == "" || utf8.RuneCountInString(card.Title) > maxCardTitleLength {
+		return invalidMessage(fmt.Sprintf("card title is required and limited to %d characters", maxCardTitleLength))
+	}
+
+	if utf8.RuneCountInString(card.Text) > maxCardTextLength {
+		return invalidMessage(fmt.Sprintf("card text exceed %d characters", maxCardTextLength))
+	}
+
+	if card.ImageURL != "" && !isWebURL(card.ImageURL) {
+		return invalidMessage("card image must be http or https url")
+	}
+
+	if len(card.Fields) > maxCardFields {
+		return invalidMessage(fmt.Sprintf("card is limited to %d fields", maxCardFields))
+	}
+
+	for _, f := range card.Fields {
+		if f == nil || strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Value) == "" {
+			return invalidMessage("card field require both name and value")
+		}
+
+		if utf8.RuneCountInString(f.Name) > maxCardFieldLength || utf8.RuneCountInString(f.Value) > maxCardFieldLength {
+			return invalidMessage(fmt.Sprintf("card field is limited to %d characters", maxCardFieldLength))
+		}
+	}
+
+	if len(card.Buttons) > maxCardButtons {
+		return invalidMessage(fmt.Sprintf("card is limited to %d buttons", maxCardButtons))
+	}
+
+	for _, b := range card.Buttons {
+		if b == nil || strings.TrimSpace(b.Label) == "" || utf8.RuneCountInString(b.Label) > maxCardButtonLength {
+			return invalidMessage(fmt.Sprintf("card button label is required and limited to %d characters", maxCardButtonLength))
+		}
+
+		if !isWebURL(b.URL) {
+			return invalidMessage("card button must link to http or https url")
+		}
+	}
+
+	return
+}
+
+// isWebURL only accept absolute http(s) url so card could never carry script or other scheme to the client
+func isWebURL(value string) bool {
+	if len(value) > maxCardURLLength {
+		return false
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// encodeMessageMeta serialize the type specific part of the envelope, plain message has no meta
+func encodeMessageMeta(env *dto.MessageEnvelope) (string, error) {
+	if env.Language == "" && env.Card == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(&messageMeta{Language: env.Language, Card: env.Card})
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// applyMessageMeta restore stored envelope into outgoing message, unreadable meta is ignored rather than failing the whole history
+func applyMessageMeta(res *indto.IncomingMessage, msg *model.ChatHistory) {
+	res.Type = msg.MessageType
+	if res.Type == "" {
+		res.Type = inconst.MessageTypeText
+	}
+
+	if msg.MessageMeta == "" {
+		return
+	}
+
+	meta := &messageMeta{}
+	if err := json.Unmarshal([]byte(msg.MessageMeta), meta); err != nil {
+		return
+	}
+
+	res.Language, res.Card = meta.Language, meta.Card
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// emoji is outside the BMP, taking 4 bytes in utf-8 and 12 bytes once escaped as surrogate pair
+const emoji = "😀"
+
+func maxURL() string {
+	prefix := "https://example.com/"
+	return prefix + strings.Repeat("a", maxCardURLLength-len(prefix))
+}
+
+// maxCard build the largest card accepted by validateCard
+func maxCard() *dto.MessageCard {
+	card := &dto.MessageCard{
+		Title:    strings.Repeat(emoji, maxCardTitleLength),
+		Text:     strings.Repeat(emoji, maxCardTextLength),
+		ImageURL: maxURL(),
+	}
+
+	for i := 0; i < maxCardFields; i++ {
+		card.Fields = append(card.Fields, &dto.MessageCardField{
+			Name:   strings.Repeat(emoji, maxCardFieldLength),
+			Value:  strings.Repeat(emoji, maxCardFieldLength),
+			Inline: true,
+		})
+	}
+
+	for i := 0; i < maxCardButtons; i++ {
+		card.Buttons = append(card.Buttons, &dto.MessageCardButton{
+			Label: strings.Repeat(emoji, maxCardButtonLength),
+			URL:   maxURL(),
+		})
+	}
+
+	return card
+}
+
+func TestValidateEnvelope(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           dto.MessageEnvelope
+		hasAttachment bool
+		wantErr       bool
+		wantType      string
+		wantContent   string
+	}{
+		{name: "missing type is text", env: dto.MessageEnvelope{Content: "hello"}, wantType: inconst.MessageTypeText, wantContent: "hello"},
+		{name: "markdown", env: dto.MessageEnvelope{Type: inconst.MessageTypeMarkdown, Content: "**hi**"}, wantType: inconst.MessageTypeMarkdown, wantContent: "**hi**"},
+		{name: "blank text", env: dto.MessageEnvelope{Content: "  "}, wantErr: true},
+		{name: "blank text with attachment", env: dto.MessageEnvelope{}, hasAttachment: true, wantType: inconst.MessageTypeText},
+		{name: "content at limit", env: dto.MessageEnvelope{Content: strings.Repeat(emoji, maxMessageLength)}, wantType: inconst.MessageTypeText, wantContent: strings.Repeat(emoji, maxMessageLength)},
+		{name: "content over limit", env: dto.MessageEnvelope{Content: strings.Repeat("a", maxMessageLength+1)}, wantErr: true},
+		{name: "code language is normalized", env: dto.MessageEnvelope{Type: inconst.MessageTypeCode, Content: "fmt.Println()", Language: "Go"}, wantType: inconst.MessageTypeCode, wantContent: "fmt.Println()"},
+		{name: "blank code with attachment", env: dto.MessageEnvelope{Type: inconst.MessageTypeCode}, hasAttachment: true, wantErr: true},
+		{name: "invalid code language", env: dto.MessageEnvelope{Type: inconst.MessageTypeCode, Content: "x", Language: "go lang"}, wantErr: true},
+		{name: "language on text", env: dto.MessageEnvelope{Content: "x", Language: "go"}, wantErr: true},
+		{name: "card on text", env: dto.MessageEnvelope{Content: "x", Card: &dto.MessageCard{Title: "t"}}, wantErr: true},
+		{name: "card without card", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Content: "x"}, wantErr: true},
+		{name: "card fallback to title", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: &dto.MessageCard{Title: "release"}}, wantType: inconst.MessageTypeCard, wantContent: "release"},
+		{name: "max card", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Content: "x", Card: maxCard()}, wantType: inconst.MessageTypeCard, wantContent: "x"},
+		{name: "card without title", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: &dto.MessageCard{Title: " "}}, wantErr: true},
+		{name: "card with too many fields", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: func() *dto.MessageCard {
+			card := maxCard()
+			card.Fields = append(card.Fields, card.Fields[0])
+			return card
+		}()}, wantErr: true},
+		{name: "card field without value", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: &dto.MessageCard{Title: "t", Fields: []*dto.MessageCardField{{Name: "n"}}}}, wantErr: true},
+		{name: "card with script button", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: &dto.MessageCard{Title: "t", Buttons: []*dto.MessageCardButton{{Label: "go", URL: "javascript:alert(1)"}}}}, wantErr: true},
+		{name: "card with nil button", env: dto.MessageEnvelope{Type: inconst.MessageTypeCard, Card: &dto.MessageCard{Title: "t", Buttons: []*dto.MessageCardButton{nil}}}, wantErr: true},
+		{name: "system is reserved", env: dto.MessageEnvelope{Type: inconst.MessageTypeSystem, Content: "x"}, wantErr: true},
+		{name: "unknown type", env: dto.MessageEnvelope{Type: "poll", Content: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvelope(&tt.env, tt.hasAttachment)
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrInvalidMsg) {
+					t.Fatalf("expected invalid message error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.env.Type != tt.wantType || tt.env.Content != tt.wantContent {
+				t.Fatalf("expected %s %q, got %s %q", tt.wantType, tt.wantContent, tt.env.Type, tt.env.Content)
+			}
+		})
+	}
+}
+
+func TestIsWebURL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "https://example.com/a.png", want: true},
+		{value: "http://example.com", want: true},
+		{value: maxURL(), want: true},
+		{value: maxURL() + "a"},
+		{value: "javascript:alert(1)"},
+		{value: "data:image/png;base64,AAAA"},
+		{value: "ftp://example.com/file"},
+		{value: "//example.com/a.png"},
+		{value: "/relative/path"},
+		{value: "https://"},
+		{value: "http://[::1"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		if got := isWebURL(tt.value); got != tt.want {
+			t.Errorf("isWebURL(%.40q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMessageMetaRoundTrip(t *testing.T) {
+	env := &dto.MessageEnvelope{Type: inconst.MessageTypeCode, Content: "x", Language: "go"}
+	meta, err := encodeMessageMeta(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &indto.IncomingMessage{}
+	applyMessageMeta(res, &model.ChatHistory{MessageType: env.Type, MessageMeta: meta})
+	if res.Type != inconst.MessageTypeCode || res.Language != "go" {
+		t.Fatalf("expected go code, got %s %q", res.Type, res.Language)
+	}
+
+	if meta, _ = encodeMessageMeta(&dto.MessageEnvelope{Content: "plain"}); meta != "" {
+		t.Fatalf("plain message should have no meta, got %q", meta)
+	}
+}
+
+func TestSendOversizedMsgOverSocket(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+
+	frame, err := json.Marshal(dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatSendRoomMsgEvent,
+		Data:      &dto.ChatRoomPayload{RoomID: room.ID, MessageEnvelope: dto.MessageEnvelope{Content: strings.Repeat("a", maxMsgSize)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = alice.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := alice.expectError(); msg != fmt.Sprintf("message exceed %d bytes", maxMsgSize) {
+		t.Fatalf("unexpected error: %q", msg)
+	}
+
+	// oversized message is dropped without closing the connection
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "still here"})
+	alice.expectIncoming("still here")
+}
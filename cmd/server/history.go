@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	defaultChatLogLimit = 50
+	maxChatLogLimit     = 200
+)
+
+// parseChatLogPayload accept either plain room/username string or a full cursor payload
+func parseChatLogPayload(data any) (res *dto.ChatLogPayload) {
+	switch v := data.(type) {
+	case string:
+		res = &dto.ChatLogPayload{RoomName: v, Username: v}
+	case map[string]any:
+		res = structutil.MapToStruct[*dto.ChatLogPayload](v)
+	}
+
+	if res == nil {
+		res = &dto.ChatLogPayload{}
+	}
+
+	normalizeChatLogLimit(res)
+	return
+}
+
+func normalizeChatLogLimit(payload *dto.ChatLogPayload) {
+	if payload.Limit == 0 {
+		payload.Limit = defaultChatLogLimit
+	} else if payload.Limit > maxChatLogLimit {
+		payload.Limit = maxChatLogLimit
+	}
+}
+
+// fetchChatLog fetch a single page of history, one extra row is queried to determine whether more page exists
+func fetchChatLog(ctx context.Context, repo inrepo.Repository, params *indto.ChatHistoryParams, payload *dto.ChatLogPayload) (res *indto.ChatLogResponse, err error) {
+	params.BeforeID = payload.BeforeID
+	params.AfterID = payload.AfterID
+	params.Limit = payload.Limit + 1
+
+	msg, err := repo.FindChatHistory(ctx, params)
+	if err != nil {
+		return
+	}
+
+	res = &indto.ChatLogResponse{}
+	if uint64(len(msg)) > payload.Limit {
+		res.HasMore = true
+
+		// extra row sit on the far end of the scan direction
+		if payload.AfterID != 0 {
+			msg = msg[:payload.Limit]
+		} else {
+			msg = msg[1:]
+		}
+	}
+
+	res.Messages = toIncomingMessages(msg)
+	if err = attachReactions(ctx, repo, res.Messages); err != nil {
+		return nil, err
+	}
+
+	if err = loadAttachments(ctx, repo, res.Messages); err != nil {
+		return nil, err
+	}
+
+	if len(msg) != 0 {
+		res.OldestID = msg[0].ID
+		res.NewestID = msg[len(msg)-1].ID
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) sendChatLog(params *indto.ChatHistoryParams, payload *dto.ChatLogPayload) {
+	res, err := fetchChatLog(lc.ctx, lc.repo, params, payload)
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to get message log")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to get message log",
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatMsgLogEvent,
+		Data:      res,
+	}
+}
+
+// toIncomingMessages map stored chat history into the same payload used by live messages
+func toIncomingMessages(msg []*model.ChatHistory) (res []*indto.IncomingMessage) {
+	res = []*indto.IncomingMessage{}
+	for _, m := range msg {
+		res = append(res, &indto.IncomingMessage{
+			ID:             m.ID,
+			SenderID:       m.SenderID,
+			SenderName:     m.SenderName,
+			RecipientID:    m.RecipientID,
+			RoomID:         m.RoomID,
+			RoomName:       m.RoomName,
+			Content:        m.Message,
+			IsDM:           m.RoomID == 0,
+			ConversationID: m.ConversationID,
+			IsSystem:       m.SenderID == 0,
+			CreatedAt:      m.CreatedAt,
+			EditedAt:       m.EditedAt,
+			DeletedAt:      m.DeletedAt,
+			ParentID:       m.ParentID,
+			ReplyCount:     m.ReplyCount,
+		})
+		applyMessageMeta(res[len(res)-1], m)
+	}
+
+	return
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+// insertTestMessages store direct messages from sender to recipient in the given order and return their ids
+func insertTestMessages(t *testing.T, repo inrepo.Repository, sender, recipient *model.User, contents ...string) (ids []int64) {
+	t.Helper()
+
+	for _, content := range contents {
+		id, err := repo.InsertChatHistory(testContext(), &model.ChatHistory{
+			SenderID:    sender.ID,
+			RecipientID: recipient.ID,
+			Message:     content,
+			MessageType: inconst.MessageTypeText,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert message: %v", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return
+}
+
+func TestDirectLogReplay(t *testing.T) {
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv.repo, "alice")
+	bob := createTestUser(t, srv.repo, "bob")
+	carol := createTestUser(t, srv.repo, "carol")
+
+	insertTestMessages(t, srv.repo, alice, bob, "hi bob")
+	insertTestMessages(t, srv.repo, carol, bob, "hi from carol")
+	insertTestMessages(t, srv.repo, bob, alice, "hi alice")
+
+	// plain username is accepted as payload, both side of the conversation are replayed oldest first
+	conn := srv.connect(t, "alice")
+	conn.send(inconst.LiveChatDirectLogEvent, "bob")
+
+	res := decodeEvent[*indto.ChatLogResponse](t, conn.expect(inconst.LiveChatMsgLogEvent))
+	if len(res.Messages) != 2 || res.Messages[0].Content != "hi bob" || res.Messages[1].Content != "hi alice" {
+		t.Fatalf("unexpected replay: %+v", res.Messages)
+	}
+
+	for _, m := range res.Messages {
+		if !m.IsDM || m.ID == 0 {
+			t.Fatalf("replayed message should carry its id and dm flag: %+v", m)
+		}
+	}
+
+	conn.send(inconst.LiveChatDirectLogEvent, "nobody")
+	if msg := conn.expectError(); msg != "recipient doesnt exists" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+}
+
+func TestRoomLogReplay(t *testing.T) {
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv.repo, "alice")
+	bob := createTestUser(t, srv.repo, "bob")
+
+	general := createTestRoom(t, srv.repo, "general", map[int64]string{alice.ID: inconst.RoomRoleOwner, bob.ID: inconst.RoomRoleMember})
+	random := createTestRoom(t, srv.repo, "random", map[int64]string{alice.ID: inconst.RoomRoleOwner})
+
+	post := func(sender *model.User, roomID int64, content string) {
+		_, err := srv.repo.InsertChatHistory(testContext(), &model.ChatHistory{
+			RoomID: roomID, SenderID: sender.ID, Message: content, MessageType: inconst.MessageTypeText, CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert message: %v", err)
+		}
+	}
+
+	post(alice, general.ID, "hi general")
+	post(alice, random.ID, "hi random")
+	post(bob, general.ID, "hey alice")
+	insertTestMessages(t, srv.repo, alice, bob, "psst")
+
+	// only the room messages are replayed oldest first, each along with its sender name
+	conn := srv.connect(t, "bob")
+	conn.send(inconst.LiveChatRoomLogEvent, "general")
+
+	res := decodeEvent[*indto.ChatLogResponse](t, conn.expect(inconst.LiveChatMsgLogEvent))
+	if len(res.Messages) != 2 {
+		t.Fatalf("expected 2 messages in the log, got %+v", res.Messages)
+	}
+
+	for i, want := range []struct{ content, sender string }{{"hi general", "alice"}, {"hey alice", "bob"}} {
+		m := res.Messages[i]
+		if m.Content != want.content || m.SenderName != want.sender || m.RoomID != general.ID || m.IsDM {
+			t.Fatalf("expected %q by %s in room %d, got %+v", want.content, want.sender, general.ID, m)
+		}
+	}
+
+	conn.send(inconst.LiveChatRoomLogEvent, map[string]any{"room_id": random.ID})
+	if msg := conn.expectError(); msg != "not joined to the room" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+
+	conn.send(inconst.LiveChatRoomLogEvent, "nowhere")
+	if msg := conn.expectError(); msg != "room doesnt exists" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+}
+
+func TestParseChatLogPayload(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  any
+		room  string
+		limit uint64
+		after int64
+	}{
+		{name: "plain name", data: "general", room: "general", limit: defaultChatLogLimit},
+		{name: "cursor", data: map[string]any{"room_name": "general", "after_id": 10, "limit": 5}, room: "general", after: 10, limit: 5},
+		{name: "limit capped", data: map[string]any{"room_name": "general", "limit": 1000}, room: "general", limit: maxChatLogLimit},
+		{name: "unknown payload", data: 42, limit: defaultChatLogLimit},
+		{name: "missing payload", limit: defaultChatLogLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := parseChatLogPayload(tt.data)
+			if res.RoomName != tt.room || res.Limit != tt.limit || res.AfterID != tt.after {
+				t.Fatalf("unexpected payload %+v", res)
+			}
+		})
+	}
+}
+
+func TestFetchChatLog(t *testing.T) {
+	repo := newTestRepo(t)
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	ids := insertTestMessages(t, repo, alice, bob, "m1", "m2", "m3", "m4", "m5")
+
+	tests := []struct {
+		name     string
+		payload  *dto.ChatLogPayload
+		expected []int64
+		hasMore  bool
+	}{
+		{name: "latest page", payload: &dto.ChatLogPayload{Limit: 2}, expected: ids[3:], hasMore: true},
+		{name: "older page", payload: &dto.ChatLogPayload{BeforeID: ids[3], Limit: 2}, expected: ids[1:3], hasMore: true},
+		{name: "oldest page", payload: &dto.ChatLogPayload{BeforeID: ids[1], Limit: 2}, expected: ids[:1]},
+		{name: "exact fit", payload: &dto.ChatLogPayload{BeforeID: ids[2], Limit: 2}, expected: ids[:2]},
+		{name: "newer page", payload: &dto.ChatLogPayload{AfterID: ids[0], Limit: 2}, expected: ids[1:3], hasMore: true},
+		{name: "newest page", payload: &dto.ChatLogPayload{AfterID: ids[2], Limit: 2}, expected: ids[3:]},
+		{name: "nothing newer", payload: &dto.ChatLogPayload{AfterID: ids[4], Limit: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := fetchChatLog(testContext(), repo, &indto.ChatHistoryParams{UserID: alice.ID, PeerID: bob.ID, IsDM: true}, tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []int64{}
+			for _, m := range res.Messages {
+				got = append(got, m.ID)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) || res.HasMore != tt.hasMore {
+				t.Fatalf("expected %v has more %v, got %v has more %v", tt.expected, tt.hasMore, got, res.HasMore)
+			}
+
+			// cursor of the page point at its own edges
+			if len(got) != 0 && (res.OldestID != got[0] || res.NewestID != got[len(got)-1]) {
+				t.Fatalf("unexpected cursor %d-%d for %v", res.OldestID, res.NewestID, got)
+			}
+		})
+	}
+}
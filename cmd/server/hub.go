@@ -1,80 +1,234 @@
 package server
 
 import (
+	"context"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
 	"github.com/nmluci/realtime-chat-sys/pkg/dto"
 	"github.com/rs/zerolog"
 )
 
 type LiveChatHub struct {
-	connectionPool map[int64]*LiveChatSocketMiddleware
+	connectionPool *connectionPool
 	rooms          *rooms
 	broadcast      chan dto.LiveChatBroadcastEvent
+	userEvent      chan dto.LiveChatUserEvent
+	typing         chan *dto.LiveChatTypingEvent
+	typingState    *typingIndicators
+	presenceChan   chan *dto.LiveChatPresenceEvent
+	presence       *presenceTracker
+	tasks          *hubTasks
 	register       chan *LiveChatSocketMiddleware
 	unregister     chan *LiveChatSocketMiddleware
 	logger         zerolog.Logger
+	repo           inrepo.Repository
 	msgChan        chan *dto.LiveChatSocketRequest
 	doneChan       chan int
 }
 
 type LiveChatHubParms struct {
 	Logger   zerolog.Logger
+	Repo     inrepo.Repository
 	MsgChan  chan *dto.LiveChatSocketRequest
 	DoneChan chan int
 }
 
 func NewLiveChatHub(params *LiveChatHubParms) *LiveChatHub {
 	return &LiveChatHub{
-		connectionPool: make(map[int64]*LiveChatSocketMiddleware),
+		connectionPool: newConnectionPool(),
 		rooms:          newRooms(),
 		broadcast:      make(chan dto.LiveChatBroadcastEvent),
+		userEvent:      make(chan dto.LiveChatUserEvent),
+		typing:         make(chan *dto.LiveChatTypingEvent),
+		typingState:    newTypingIndicators(),
+		presenceChan:   make(chan *dto.LiveChatPresenceEvent),
+		presence:       newPresenceTracker(),
+		tasks:          newHubTasks(),
 		register:       make(chan *LiveChatSocketMiddleware),
 		unregister:     make(chan *LiveChatSocketMiddleware),
 		logger:         params.Logger,
+		repo:           params.Repo,
 		msgChan:        params.MsgChan,
 		doneChan:       params.DoneChan,
 	}
 }
 
 func (lc *LiveChatHub) Run() {
+	typingTicker := time.NewTicker(typingSweepInterval)
+	defer typingTicker.Stop()
+
+	go lc.tasks.run()
+
 	for {
 		select {
 		case conn := <-lc.register:
-			lc.connectionPool[conn.UserID] = conn
+			wasOnline := lc.connectionPool.isOnline(conn.UserID)
+			lc.connectionPool.add(conn)
+
+			if !wasOnline {
+				lc.publishPresence(&indto.PresenceInfo{UserID: conn.UserID, Username: conn.username, Status: inconst.PresenceOnline})
+			}
 		case conn := <-lc.unregister:
-			if _, ok := lc.connectionPool[conn.UserID]; ok {
-				delete(lc.connectionPool, conn.UserID)
-				close(conn.in)
+			lc.dropConn(conn)
+
+			// last device disconnected
+			if !lc.connectionPool.isOnline(conn.UserID) {
+				for _, ev := range lc.typingState.expire(time.Now(), conn.UserID) {
+					lc.relayTyping(ev)
+				}
+
+				lc.markOffline(conn)
+			}
+		case ev := <-lc.presenceChan:
+			lc.presence.setAway(ev.UserID, ev.Status == inconst.PresenceAway)
+			lc.publishPresence(&indto.PresenceInfo{UserID: ev.UserID, Username: ev.Username, Status: lc.PresenceOf(ev.UserID)})
+		case ev := <-lc.typing:
+			if lc.typingState.update(ev) {
+				lc.relayTyping(ev)
+			}
+		case now := <-typingTicker.C:
+			for _, ev := range lc.typingState.expire(now, 0) {
+				lc.relayTyping(ev)
 			}
 		case msg := <-lc.broadcast:
-			connMap := lc.rooms.getRoom(msg.Room)
-
+			// tag the event with its origin room so client could route it
 			event := msg.Event
-			for k, conn := range connMap {
-				select {
-				case conn.in <- event:
-				default:
-					close(conn.in)
-					delete(lc.connectionPool, k)
-				}
+			event.RoomID = msg.Room
+			for _, conn := range lc.rooms.getRoom(msg.Room) {
+				lc.deliver(conn, event)
 			}
+		case msg := <-lc.userEvent:
+			lc.sendToUser(msg.UserID, msg.Event)
 		case msg := <-lc.msgChan:
-			recipient, ok := lc.connectionPool[msg.RecipientID]
-			if !ok {
-				lc.logger.Warn().Msg("message dropped due to recipient unreachable")
-				continue
+			lc.dispatchDirect(msg)
+		}
+
+	}
+}
+
+// dispatchDirect deliver direct message to every online recipient, then report per recipient status to the sender
+func (lc *LiveChatHub) dispatchDirect(msg *dto.LiveChatSocketRequest) {
+	recipientIDs := msg.RecipientIDs
+	if len(recipientIDs) == 0 {
+		recipientIDs = []int64{msg.RecipientID}
+	}
+
+	statuses := []*indto.DeliveryStatus{}
+	echoSender := true
+	for _, recipientID := range recipientIDs {
+		status := inconst.DeliveryStatusQueued
+		if lc.connectionPool.isOnline(recipientID) {
+			for _, conn := range lc.connectionPool.getUserConns(recipientID) {
+				if conn.dedupe.claimLive(msg.MessageID) {
+					lc.deliver(conn, msg.Event)
+				}
 			}
+			lc.markDelivered(recipientID, msg.MessageID)
+			status = inconst.DeliveryStatusDelivered
+		}
 
-			recipient.in <- msg.Event
-			lc.connectionPool[msg.SenderID].in <- msg.Event
+		if recipientID == msg.SenderID {
+			echoSender = false
 		}
 
+		statuses = append(statuses, &indto.DeliveryStatus{
+			MessageID:      msg.MessageID,
+			ConversationID: msg.ConversationID,
+			RecipientID:    recipientID,
+			Status:         status,
+		})
+	}
+
+	// echo to every sender device as well, so the conversation stay in sync across devices
+	if echoSender {
+		lc.sendToUser(msg.SenderID, msg.Event)
+	}
+
+	for _, status := range statuses {
+		lc.sendToUser(msg.SenderID, dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatDirectStatusEvent,
+			Data:      status,
+		})
 	}
 }
 
+// markDelivered flag live-delivered message so it won't be flushed again on next login
+func (lc *LiveChatHub) markDelivered(recipientID int64, messageID int64) {
+	if messageID == 0 {
+		return
+	}
+
+	lc.tasks.push(func() {
+		ctx := lc.logger.WithContext(context.Background())
+		err := lc.repo.MarkMessageDelivered(ctx, &indto.MessageDeliveryParams{RecipientID: recipientID, MessageIDs: []int64{messageID}})
+		if err != nil {
+			lc.logger.Error().Err(err).Msg("failed to mark message as delivered")
+		}
+	})
+}
+
+// deliver push event to connection without blocking the hub, slow connection is dropped instead
+func (lc *LiveChatHub) deliver(conn *LiveChatSocketMiddleware, event dto.LiveChatSocketEvent) {
+	select {
+	case conn.in <- event:
+	default:
+		lc.logger.Warn().Str("username", conn.username).Msg("connection dropped due to full buffer")
+		lc.evictConn(conn)
+	}
+}
+
+func (lc *LiveChatHub) sendToUser(userID int64, event dto.LiveChatSocketEvent) {
+	for _, conn := range lc.connectionPool.getUserConns(userID) {
+		lc.deliver(conn, event)
+	}
+}
+
+// dropConn remove closed connection from hub, called once the connection reader exited
+func (lc *LiveChatHub) dropConn(conn *LiveChatSocketMiddleware) {
+	if !lc.connectionPool.remove(conn) {
+		return // already evicted
+	}
+
+	lc.rooms.leaveAllRooms(conn)
+	close(conn.in)
+}
+
+// evictConn forcefully remove connection from hub and close the underlying socket,
+// the reader will exit on its own hence the outbound channel is left for the writer to drain
+func (lc *LiveChatHub) evictConn(conn *LiveChatSocketMiddleware) {
+	if !lc.connectionPool.remove(conn) {
+		return
+	}
+
+	lc.rooms.leaveAllRooms(conn)
+	conn.conn.Close()
+}
+
+// SendToUser push event to every connected device of the user
+func (lc *LiveChatHub) SendToUser(userID int64, event dto.LiveChatSocketEvent) {
+	lc.userEvent <- dto.LiveChatUserEvent{UserID: userID, Event: event}
+}
+
 func (lc *LiveChatHub) JoinRoom(roomID int64, conn *LiveChatSocketMiddleware) {
 	lc.rooms.joinRoom(roomID, conn)
 }
 
-func (lc *LiveChatHub) LeaveRoom(roomID int64, conn *LiveChatSocketMiddleware) {
-	lc.rooms.leaveRoom(roomID, conn)
+// JoinUserRoom subscribe every connected device of the user to the room
+func (lc *LiveChatHub) JoinUserRoom(roomID int64, userID int64) {
+	for _, conn := range lc.connectionPool.getUserConns(userID) {
+		lc.rooms.joinRoom(roomID, conn)
+	}
+}
+
+// OnlineCount return number of users currently connected to the room
+func (lc *LiveChatHub) OnlineCount(roomID int64) int {
+	return lc.rooms.onlineCount(roomID)
+}
+
+// LeaveUserRoom unsubscribe every connected device of the user from the room
+func (lc *LiveChatHub) LeaveUserRoom(roomID int64, userID int64) {
+	lc.rooms.leaveUserRooms(roomID, userID)
 }
@@ -0,0 +1,51 @@
+package server
+
+import "sync"
+
+// hubTasks run side effects of the hub which touch the database in the order they were queued, outside the hub loop
+// so slow query never stall delivery. The queue is unbounded since the loop must never block on it while the tasks
+// themselves push events back through the loop
+type hubTasks struct {
+	queue  []func()
+	signal chan struct{}
+
+	mutex sync.Mutex
+}
+
+func newHubTasks() *hubTasks {
+	return &hubTasks{
+		signal: make(chan struct{}, 1),
+	}
+}
+
+func (t *hubTasks) push(task func()) {
+	t.mutex.Lock()
+	t.queue = append(t.queue, task)
+	t.mutex.Unlock()
+
+	select {
+	case t.signal <- struct{}{}:
+	default: // worker is already notified
+	}
+}
+
+func (t *hubTasks) pop() (task func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.queue) == 0 {
+		return nil
+	}
+
+	task, t.queue[0] = t.queue[0], nil
+	t.queue = t.queue[1:]
+	return
+}
+
+func (t *hubTasks) run() {
+	for range t.signal {
+		for task := t.pop(); task != nil; task = t.pop() {
+			task()
+		}
+	}
+}
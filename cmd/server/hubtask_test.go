@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+)
+
+func TestHubTasksOrder(t *testing.T) {
+	tasks := newHubTasks()
+	go tasks.run()
+
+	mu := sync.Mutex{}
+	done := make(chan struct{})
+	res := []int{}
+
+	release := make(chan struct{})
+	tasks.push(func() { <-release })
+
+	// pushing never wait for the running task
+	for i := 0; i < 100; i++ {
+		tasks.push(func() {
+			mu.Lock()
+			res = append(res, i)
+			mu.Unlock()
+
+			if i == 99 {
+				close(done)
+			}
+		})
+	}
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks are not run")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, v := range res {
+		if i != v {
+			t.Fatalf("tasks run out of order: %v", res)
+		}
+	}
+}
+
+// slowContactsRepo hold contact lookup until released, simulating slow query
+type slowContactsRepo struct {
+	inrepo.Repository
+	release chan struct{}
+}
+
+func (r *slowContactsRepo) FindUserContacts(ctx context.Context, params *indto.UserParams) ([]int64, error) {
+	<-r.release
+	return r.Repository.FindUserContacts(ctx, params)
+}
+
+func TestHubPresenceOffLoop(t *testing.T) {
+	repo := &slowContactsRepo{Repository: newTestRepo(t), release: make(chan struct{})}
+	srv := newTestServerWithRepo(t, repo)
+
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+	bobUser, _ := repo.FindUser(testContext(), &indto.UserParams{Username: "bob"})
+	carolUser, _ := repo.FindUser(testContext(), &indto.UserParams{Username: "carol"})
+
+	// presence of every login is stuck resolving contacts, message must still be delivered meanwhile
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "hi"})
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "hi" {
+		t.Fatalf("expected hi, got %q", msg.Content)
+	}
+	close(repo.release)
+
+	// carol only share a group conversation with bob
+	_, err := repo.InsertConversation(testContext(), &model.Conversation{
+		ParticipantKey: "bob,carol",
+		CreatedBy:      bobUser.ID,
+		CreatedAt:      time.Now(),
+		Participants:   []*model.ConversationParticipant{{UserID: bobUser.ID}, {UserID: carolUser.ID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bob.conn.Close()
+
+	for {
+		info := decodeEvent[*indto.PresenceInfo](t, carol.expect(inconst.LiveChatPresenceEvent))
+		if info.UserID != bobUser.ID || info.Status != inconst.PresenceOffline {
+			continue
+		}
+
+		if info.LastSeenAt == nil {
+			t.Fatal("offline presence should carry last seen")
+		}
+		break
+	}
+
+	// last seen is persisted by the time contacts are notified
+	users, err := repo.FindUsers(testContext(), &indto.UserParams{IDs: []int64{bobUser.ID}})
+	if err != nil || len(users) != 1 || users[0].LastSeenAt == nil {
+		t.Fatalf("last seen of bob is not persisted: %v", err)
+	}
+
+	// the message is flagged as delivered off the loop as well
+	pending, err := repo.FindUndeliveredMessages(testContext(), &indto.MessageDeliveryParams{RecipientID: bobUser.ID})
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("live delivered message should not be pending, got %d err %v", len(pending), err)
+	}
+}
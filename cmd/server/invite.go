@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+func generateInviteCode() (string, error) {
+	buf := make([]byte, 9)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ensureInviteCode return current invite code of the room, new code is generated when none exists or reset is requested
+func ensureInviteCode(ctx context.Context, repo inrepo.Repository, roomMeta *model.ChatRoom, reset bool) (err error) {
+	if roomMeta.InviteCode != "" && !reset {
+		return
+	}
+
+	roomMeta.InviteCode, err = generateInviteCode()
+	if err != nil {
+		return
+	}
+
+	return repo.UpdateRoom(ctx, roomMeta)
+}
+
+func (lc *LiveChatSocketMiddleware) sendRoomManageError(err error) {
+	msg := "failed to update room"
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		msg = "invalid room request"
+	case errors.Is(err, errs.ErrNotFound):
+		msg = "room or user doesnt exists"
+	case errors.Is(err, errs.ErrForbidden):
+		msg = "not allowed to manage the room"
+	default:
+		lc.logger.Error().Err(err).Msg("failed to update room")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      msg,
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) parseRoomInvitePayload(event *dto.LiveChatSocketEvent) *dto.RoomInvitePayload {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid invite payload",
+		}
+		return nil
+	}
+
+	// lookup without any room would resolve into an arbitrary room
+	payload := structutil.MapToStruct[*dto.RoomInvitePayload](data)
+	if payload.RoomID == 0 && payload.RoomName == "" {
+		lc.sendRoomManageError(errs.ErrBadRequest)
+		return nil
+	}
+
+	return payload
+}
+
+// handleRoomInvite let room owner invite a user, the invitee is notified on every connected device
+func (lc *LiveChatSocketMiddleware) handleRoomInvite(event *dto.LiveChatSocketEvent) {
+	payload := lc.parseRoomInvitePayload(event)
+	if payload == nil {
+		return
+	}
+
+	roomMeta, err := findManagedRoom(lc.ctx, lc.repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, lc.UserID, inconst.RoomRoleOwner)
+	if err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	invitee, err := lc.repo.FindUser(lc.ctx, &indto.UserParams{Username: payload.Username})
+	if err != nil {
+		lc.sendRoomManageError(err)
+		return
+	} else if invitee == nil || invitee.ID == lc.UserID {
+		lc.sendRoomManageError(errs.ErrNotFound)
+		return
+	}
+
+	invite := &model.RoomInvite{
+		RoomID:      roomMeta.ID,
+		RoomName:    roomMeta.RoomName,
+		Visibility:  roomMeta.Visibility,
+		UserID:      invitee.ID,
+		InvitedBy:   lc.UserID,
+		InviterName: lc.username,
+		CreatedAt:   time.Now(),
+	}
+	if err = lc.repo.InsertRoomInvite(lc.ctx, invite); err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	lc.hub.SendToUser(invitee.ID, dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatInvitedEvent,
+		Data:      toRoomInviteInfos([]*model.RoomInvite{invite})[0],
+	})
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatInviteEvent,
+		Data:      &indto.UserInfo{ID: invitee.ID, Username: invitee.Username},
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleDeclineInvite(event *dto.LiveChatSocketEvent) {
+	payload := lc.parseRoomInvitePayload(event)
+	if payload == nil {
+		return
+	}
+
+	roomMeta, err := lc.repo.FindRoom(lc.ctx, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName})
+	if err != nil {
+		lc.sendRoomManageError(err)
+		return
+	} else if roomMeta == nil {
+		lc.sendRoomManageError(errs.ErrNotFound)
+		return
+	}
+
+	if err = lc.repo.DeleteRoomInvite(lc.ctx, &indto.RoomInviteParams{RoomID: roomMeta.ID, UserID: lc.UserID}); err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	lc.sendInviteList()
+}
+
+func (lc *LiveChatSocketMiddleware) sendInviteList() {
+	invites, err := lc.repo.FindRoomInvites(lc.ctx, &indto.RoomInviteParams{UserID: lc.UserID})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch room invites")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch room invites",
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatInviteListEvent,
+		Data:      toRoomInviteInfos(invites),
+	}
+}
+
+// handleInviteCode return the room invite code to its owner, optionally rotating it
+func (lc *LiveChatSocketMiddleware) handleInviteCode(event *dto.LiveChatSocketEvent) {
+	payload := lc.parseRoomInvitePayload(event)
+	if payload == nil {
+		return
+	}
+
+	roomMeta, err := findManagedRoom(lc.ctx, lc.repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, lc.UserID, inconst.RoomRoleOwner)
+	if err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	if err = ensureInviteCode(lc.ctx, lc.repo, roomMeta, payload.Reset); err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatInviteCodeEvent,
+		Data:      &indto.RoomInviteCode{RoomID: roomMeta.ID, RoomName: roomMeta.RoomName, InviteCode: roomMeta.InviteCode},
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleSetVisibility(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid visibility payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.RoomVisibilityPayload](data)
+
+	if !isValidVisibility(payload.Visibility) {
+		lc.sendRoomManageError(errs.ErrBadRequest)
+		return
+	}
+
+	roomMeta, err := findManagedRoom(lc.ctx, lc.repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, lc.UserID, inconst.RoomRoleOwner)
+	if err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	roomMeta.Visibility = payload.Visibility
+	if err = lc.repo.UpdateRoom(lc.ctx, roomMeta); err != nil {
+		lc.sendRoomManageError(err)
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatSetVisibilityEvent,
+		Data:      toRoomInfos([]*model.ChatRoom{roomMeta})[0],
+	}
+}
+
+// HandleJoinByInviteCode join the session user into room referred by an invite link
+func HandleJoinByInviteCode(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		code := c.Param("code")
+		if code == "" {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		roomMeta, err := joinRoom(ctx, params.Repo, params.Hub, sessionUser(c), &indto.ChatRoomParams{InviteCode: code})
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to join room")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: toRoomInfos([]*model.ChatRoom{roomMeta})[0]})
+	}
+}
+
+func toRoomInviteInfos(invites []*model.RoomInvite) (res []*indto.RoomInviteInfo) {
+	res = []*indto.RoomInviteInfo{}
+	for _, i := range invites {
+		res = append(res, &indto.RoomInviteInfo{
+			RoomID:      i.RoomID,
+			RoomName:    i.RoomName,
+			Visibility:  i.Visibility,
+			InvitedBy:   i.InvitedBy,
+			InviterName: i.InviterName,
+			CreatedAt:   i.CreatedAt,
+		})
+	}
+
+	return
+}
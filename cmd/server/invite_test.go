@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestDeclineInvite(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+
+	// another pending invite make sure rejected request never fall into arbitrary room
+	alice.createRoom(&dto.CreateRoomPayload{RoomName: "lobby", Visibility: inconst.RoomVisibilityPrivate})
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "secret", Visibility: inconst.RoomVisibilityPrivate})
+	for _, name := range []string{"lobby", "secret"} {
+		alice.send(inconst.LiveChatInviteEvent, map[string]any{"room_name": name, "username": "bob"})
+		alice.expect(inconst.LiveChatInviteEvent)
+		bob.expect(inconst.LiveChatInvitedEvent)
+	}
+
+	for _, event := range []string{inconst.LiveChatDeclineInviteEvent, inconst.LiveChatInviteEvent, inconst.LiveChatInviteCodeEvent} {
+		bob.send(event, map[string]any{"username": "alice"})
+		if msg := bob.expectError(); msg != "invalid room request" {
+			t.Fatalf("%s without room: expected invalid room request, got %q", event, msg)
+		}
+	}
+
+	bob.send(inconst.LiveChatListInviteEvent, nil)
+	invites := decodeEvent[[]*indto.RoomInviteInfo](t, bob.expect(inconst.LiveChatInviteListEvent))
+	if len(invites) != 2 {
+		t.Fatalf("expected both invites to be kept, got %d", len(invites))
+	}
+
+	bob.send(inconst.LiveChatDeclineInviteEvent, map[string]any{"room_id": room.ID})
+	invites = decodeEvent[[]*indto.RoomInviteInfo](t, bob.expect(inconst.LiveChatInviteListEvent))
+	if len(invites) != 1 || invites[0].RoomName != "lobby" {
+		t.Fatalf("expected only lobby invite left, got %+v", invites)
+	}
+}
+
+func TestJoinRoomVisibility(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := testContext()
+	owner := createTestUser(t, srv.repo, "owner")
+	bob := createTestUser(t, srv.repo, "bob")
+
+	rooms := map[string]*model.ChatRoom{}
+	for _, visibility := range []string{inconst.RoomVisibilityPublic, inconst.RoomVisibilityInviteOnly, inconst.RoomVisibilityPrivate} {
+		room := createTestRoom(t, srv.repo, visibility, map[int64]string{owner.ID: inconst.RoomRoleOwner})
+		room.Visibility = visibility
+		if err := ensureInviteCode(ctx, srv.repo, room, false); err != nil {
+			t.Fatal(err)
+		}
+		rooms[visibility] = room
+	}
+
+	tests := []struct {
+		name       string
+		visibility string
+		invited    bool
+		code       string
+		err        error
+	}{
+		{name: "public", visibility: inconst.RoomVisibilityPublic},
+		{name: "invite only without invite", visibility: inconst.RoomVisibilityInviteOnly, err: errs.ErrForbidden},
+		{name: "invite only with invite", visibility: inconst.RoomVisibilityInviteOnly, invited: true},
+		{name: "private without invite", visibility: inconst.RoomVisibilityPrivate, err: errs.ErrNotFound},
+		{name: "private with invite", visibility: inconst.RoomVisibilityPrivate, invited: true},
+		{name: "private with invite code", visibility: inconst.RoomVisibilityPrivate, code: "valid"},
+		{name: "unknown invite code", visibility: inconst.RoomVisibilityPrivate, code: "unknown", err: errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := rooms[tt.visibility]
+			memberParams := &indto.RoomParticipantParams{RoomID: room.ID, UserID: bob.ID}
+			t.Cleanup(func() { srv.repo.DeleteRoomParticipant(ctx, memberParams) })
+
+			if tt.invited {
+				err := srv.repo.InsertRoomInvite(ctx, &model.RoomInvite{RoomID: room.ID, UserID: bob.ID, InvitedBy: owner.ID, CreatedAt: time.Now()})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			params := &indto.ChatRoomParams{ID: room.ID}
+			if tt.code == "valid" {
+				params = &indto.ChatRoomParams{InviteCode: room.InviteCode}
+			} else if tt.code != "" {
+				params = &indto.ChatRoomParams{InviteCode: tt.code}
+			}
+
+			joined, err := joinRoom(ctx, srv.repo, srv.hub, bob, params)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			} else if err != nil {
+				return
+			}
+
+			if joined.ID != room.ID {
+				t.Fatalf("joined room %d instead of %d", joined.ID, room.ID)
+			}
+
+			// invite is consumed once joined
+			invite, err := srv.repo.FindRoomInvite(ctx, &indto.RoomInviteParams{RoomID: room.ID, UserID: bob.ID})
+			if err != nil || invite != nil {
+				t.Fatalf("invite should be consumed, got %+v err %v", invite, err)
+			}
+		})
+	}
+
+	// rotated code no longer admit anyone
+	private := rooms[inconst.RoomVisibilityPrivate]
+	oldCode := private.InviteCode
+	if err := ensureInviteCode(ctx, srv.repo, private, false); err != nil || private.InviteCode != oldCode {
+		t.Fatalf("existing code should be kept, got %q err %v", private.InviteCode, err)
+	}
+
+	if err := ensureInviteCode(ctx, srv.repo, private, true); err != nil || private.InviteCode == oldCode {
+		t.Fatalf("code should be rotated, got %q err %v", private.InviteCode, err)
+	}
+
+	if _, err := joinRoom(ctx, srv.repo, srv.hub, bob, &indto.ChatRoomParams{InviteCode: oldCode}); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("rotated code should be rejected, got %v", err)
+	}
+}
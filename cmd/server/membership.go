@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// rejoinRooms subscribe a freshly authenticated connection to every room the user is a member of
+func (lc *LiveChatSocketMiddleware) rejoinRooms() {
+	rooms, err := lc.repo.FindRooms(lc.ctx, &indto.ChatRoomParams{UserID: lc.UserID})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch joined rooms")
+		return
+	}
+
+	for _, room := range rooms {
+		lc.hub.JoinRoom(room.ID, lc)
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatRoomListEvent,
+		Data:      toRoomInfos(rooms),
+	}
+}
+
+// findMemberRoom resolve room by id or name and ensure the user is a member of it
+func findMemberRoom(ctx context.Context, repo inrepo.Repository, params *indto.ChatRoomParams, userID int64) (roomMeta *model.ChatRoom, err error) {
+	if params.ID == 0 && params.RoomName == "" {
+		return nil, errs.ErrBadRequest
+	}
+
+	roomMeta, err = repo.FindRoom(ctx, params)
+	if err != nil {
+		return nil, err
+	} else if roomMeta == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	participant, err := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: roomMeta.ID, UserID: userID})
+	if err != nil {
+		return nil, err
+	} else if participant == nil {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
+
+// findJoinedRoom is findMemberRoom for socket connection,
+// error is reported back to the client and nil is returned when either check failed
+func (lc *LiveChatSocketMiddleware) findJoinedRoom(params *indto.ChatRoomParams) *model.ChatRoom {
+	roomMeta, err := findMemberRoom(lc.ctx, lc.repo, params, lc.UserID)
+	if err != nil {
+		msg := "failed to fetch room data"
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			msg = "room is not specified"
+		case errors.Is(err, errs.ErrNotFound):
+			msg = "room doesnt exists"
+		case errors.Is(err, errs.ErrForbidden):
+			msg = "not joined to the room"
+		default:
+			lc.logger.Error().Err(err).Msg("failed to fetch room data")
+		}
+
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      msg,
+		}
+		return nil
+	}
+
+	return roomMeta
+}
+
+func (lc *LiveChatSocketMiddleware) sendRoomList() {
+	rooms, err := lc.repo.FindRooms(lc.ctx, &indto.ChatRoomParams{UserID: lc.UserID})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch joined rooms")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch joined rooms",
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatRoomListEvent,
+		Data:      toRoomInfos(rooms),
+	}
+}
+
+func toRoomInfos(rooms []*model.ChatRoom) (res []*indto.RoomInfo) {
+	res = []*indto.RoomInfo{}
+	for _, r := range rooms {
+		res = append(res, &indto.RoomInfo{
+			ID:          r.ID,
+			RoomName:    r.RoomName,
+			Topic:       r.Topic,
+			Description: r.Description,
+			Visibility:  r.Visibility,
+			CreatedBy:   r.CreatedBy,
+			CreatorName: r.CreatorName,
+			CreatedAt:   r.CreatedAt,
+		})
+	}
+
+	return
+}
+
+func isValidVisibility(visibility string) bool {
+	switch visibility {
+	case inconst.RoomVisibilityPublic, inconst.RoomVisibilityInviteOnly, inconst.RoomVisibilityPrivate:
+		return true
+	}
+
+	return false
+}
+
+// createRoom register a new room owned by the user, the owner is joined right away
+func createRoom(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, user *model.User, payload *dto.CreateRoomPayload) (roomMeta *model.ChatRoom, err error) {
+	if payload.Visibility == "" {
+		payload.Visibility = inconst.RoomVisibilityPublic
+	}
+
+	if payload.RoomName == "" || !isValidVisibility(payload.Visibility) {
+		return nil, errs.ErrBadRequest
+	}
+
+	now := time.Now()
+	roomMeta = &model.ChatRoom{
+		RoomName:    payload.RoomName,
+		Topic:       payload.Topic,
+		Description: payload.Description,
+		CreatedBy:   user.ID,
+		CreatorName: user.Username,
+		Visibility:  payload.Visibility,
+		CreatedAt:   &now,
+	}
+	// room name is kept unique by the database, taken name is reported as ErrRoomExisted
+	roomMeta.ID, err = repo.CreateRoom(ctx, roomMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.InsertRoomParticipant(ctx, &model.RoomParticipant{RoomID: roomMeta.ID, UserID: user.ID, Role: inconst.RoomRoleOwner})
+	if err != nil {
+		return nil, err
+	}
+
+	hub.JoinUserRoom(roomMeta.ID, user.ID)
+	return
+}
+
+// joinRoom add the user as a member of room resolved by id, name or invite code,
+// non public room require either a pending invite or its invite code and private room is hidden otherwise
+func joinRoom(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, user *model.User, params *indto.ChatRoomParams) (roomMeta *model.ChatRoom, err error) {
+	lookup := &indto.ChatRoomParams{ID: params.ID, RoomName: params.RoomName}
+	if params.InviteCode != "" {
+		lookup = &indto.ChatRoomParams{InviteCode: params.InviteCode}
+	} else if params.ID == 0 && params.RoomName == "" {
+		return nil, errs.ErrBadRequest
+	}
+
+	roomMeta, err = repo.FindRoom(ctx, lookup)
+	if err != nil {
+		return nil, err
+	} else if roomMeta == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	memberParams := &indto.RoomParticipantParams{RoomID: roomMeta.ID, UserID: user.ID}
+
+	participant, err := repo.FindRoomParticipant(ctx, memberParams)
+	if err != nil {
+		return nil, err
+	} else if participant != nil {
+		hub.JoinUserRoom(roomMeta.ID, user.ID)
+		return
+	}
+
+	ban, err := repo.FindRoomBan(ctx, memberParams)
+	if err != nil {
+		return nil, err
+	} else if ban != nil {
+		return nil, errs.ErrBanned
+	}
+
+	inviteParams := &indto.RoomInviteParams{RoomID: roomMeta.ID, UserID: user.ID}
+	if roomMeta.Visibility != inconst.RoomVisibilityPublic && params.InviteCode == "" {
+		invite, err := repo.FindRoomInvite(ctx, inviteParams)
+		if err != nil {
+			return nil, err
+		} else if invite == nil && roomMeta.Visibility == inconst.RoomVisibilityPrivate {
+			return nil, errs.ErrNotFound
+		} else if invite == nil {
+			return nil, errs.ErrForbidden
+		}
+	}
+
+	err = repo.InsertRoomParticipant(ctx, &model.RoomParticipant{RoomID: roomMeta.ID, UserID: user.ID, Role: inconst.RoomRoleMember})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = repo.DeleteRoomInvite(ctx, inviteParams); err != nil {
+		return nil, err
+	}
+
+	hub.JoinUserRoom(roomMeta.ID, user.ID)
+	return
+}
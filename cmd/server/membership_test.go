@@ -0,0 +1,141 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestLeaveRoom(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+
+	for _, data := range []any{map[string]any{}, "", nil} {
+		alice.send(inconst.LiveChatLeaveRoomEvent, data)
+		if msg := alice.expectError(); msg != "room is not specified" {
+			t.Fatalf("leave with %#v: expected room is not specified, got %q", data, msg)
+		}
+	}
+
+	alice.send(inconst.LiveChatLeaveRoomEvent, map[string]any{"room_name": "random"})
+	if msg := alice.expectError(); msg != "room doesnt exists" {
+		t.Fatalf("expected room doesnt exists, got %q", msg)
+	}
+
+	participant, err := srv.repo.FindRoomParticipant(testContext(), &indto.RoomParticipantParams{RoomID: room.ID, UserID: room.CreatedBy})
+	if err != nil || participant == nil {
+		t.Fatalf("membership should be kept after rejected leave: %v", err)
+	}
+
+	alice.send(inconst.LiveChatLeaveRoomEvent, map[string]any{"room_id": room.ID})
+	left := decodeEvent[*indto.RoomInfo](t, alice.expect(inconst.LiveChatLeftEvent))
+	if left.ID != room.ID {
+		t.Fatalf("expected to leave room %d, left %d", room.ID, left.ID)
+	}
+
+	participant, err = srv.repo.FindRoomParticipant(testContext(), &indto.RoomParticipantParams{RoomID: room.ID, UserID: room.CreatedBy})
+	if err != nil || participant != nil {
+		t.Fatalf("membership should be removed after leave: %v", err)
+	}
+}
+
+func TestRejoinRoomsOnLogin(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+
+	bob := srv.connect(t, "bob")
+	bob.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "hi"})
+	if msg := bob.expectError(); msg != "not joined to the room" {
+		t.Fatalf("expected not joined to the room, got %q", msg)
+	}
+
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+	bob.conn.Close()
+
+	// membership outlive the connection, the room is rejoined without asking
+	bob = srv.connect(t, "bob")
+	rooms := decodeEvent[[]*indto.RoomInfo](t, bob.expect(inconst.LiveChatRoomListEvent))
+	if len(rooms) != 1 || rooms[0].ID != room.ID {
+		t.Fatalf("expected general to be rejoined, got %+v", rooms)
+	}
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "welcome back"})
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "welcome back" {
+		t.Fatalf("expected welcome back, got %q", msg.Content)
+	}
+}
+
+func TestMultipleJoinedRooms(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	general := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	random := alice.createRoom(&dto.CreateRoomPayload{RoomName: "random"})
+
+	bob := srv.connect(t, "bob")
+	for _, name := range []string{"general", "random"} {
+		bob.send(inconst.LiveChatJoinRoomEvent, name)
+		bob.expect(inconst.LiveChatJoinedEvent)
+	}
+
+	bob.send(inconst.LiveChatListRoomEvent, nil)
+	if rooms := decodeEvent[[]*indto.RoomInfo](t, bob.expect(inconst.LiveChatRoomListEvent)); len(rooms) != 2 {
+		t.Fatalf("expected both rooms listed, got %+v", rooms)
+	}
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": general.ID, "content": "in general"})
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": random.ID, "content": "in random"})
+	for _, expected := range []int64{general.ID, random.ID} {
+		if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.RoomID != expected {
+			t.Fatalf("expected message of room %d, got %d", expected, msg.RoomID)
+		}
+	}
+
+	// leaving one room keep the other subscribed
+	bob.send(inconst.LiveChatLeaveRoomEvent, "general")
+	bob.expect(inconst.LiveChatLeftEvent)
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": general.ID, "content": "gone"})
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": random.ID, "content": "still here"})
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "still here" {
+		t.Fatalf("expected only message of random, got %q", msg.Content)
+	}
+}
+
+func TestPlainTextRoomMessage(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	general := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	alice.createRoom(&dto.CreateRoomPayload{RoomName: "random"})
+
+	bob := srv.connect(t, "bob")
+	bob.send(inconst.LiveChatSendRoomMsgEvent, "hello?")
+	if msg := bob.expectError(); msg != "not joined to any room" {
+		t.Fatalf("expected not joined to any room, got %q", msg)
+	}
+
+	// plain text go to the room joined last
+	for _, name := range []string{"random", "general"} {
+		bob.send(inconst.LiveChatJoinRoomEvent, name)
+		bob.expect(inconst.LiveChatJoinedEvent)
+	}
+
+	bob.send(inconst.LiveChatSendRoomMsgEvent, "hi all")
+	if msg := alice.expectIncoming("hi all"); msg.RoomID != general.ID || msg.Type != inconst.MessageTypeText {
+		t.Fatalf("expected text message in general, got %+v", msg)
+	}
+
+	bob.send(inconst.LiveChatLeaveRoomEvent, nil)
+	if left := decodeEvent[*indto.RoomInfo](t, bob.expect(inconst.LiveChatLeftEvent)); left.ID != general.ID {
+		t.Fatalf("expected to leave general, left %d", left.ID)
+	}
+
+	bob.send(inconst.LiveChatSendRoomMsgEvent, "anyone?")
+	if msg := bob.expectError(); msg != "not joined to any room" {
+		t.Fatalf("expected not joined to any room after leaving, got %q", msg)
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	roomMentionKeyword    = "room"
+	maxMentionsPerMessage = 50
+	defaultMentionLimit   = 20
+	maxMentionLimit       = 100
+)
+
+// mentionPattern match @username which isn't part of another word, so email address is never treated as mention
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.\-]+)`)
+
+// parseMentions extract unique mentioned usernames, trailing punctuation is dropped so "@alice." mention alice
+func parseMentions(content string) (usernames []string, roomMention bool) {
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" {
+			continue
+		}
+
+		if name == roomMentionKeyword {
+			roomMention = true
+		} else if !slices.Contains(usernames, name) && len(usernames) < maxMentionsPerMessage {
+			usernames = append(usernames, name)
+		}
+	}
+
+	return
+}
+
+// resolveMentions find users mentioned by the message, only users who could read the message are mentioned
+// except in public room where anyone could join to read it. @room mention every member of the room or conversation
+func resolveMentions(ctx context.Context, repo inrepo.Repository, msg *outgoingMessage) (res []*model.Mention, err error) {
+	// code snippet commonly contains @ for decorators or annotations
+	if msg.Envelope.Type == inconst.MessageTypeCode {
+		return
+	}
+
+	usernames, roomMention := parseMentions(msg.Envelope.Content)
+	if len(usernames) == 0 && !roomMention {
+		return
+	}
+
+	audience := map[int64]bool{}
+	if msg.Room != nil {
+		participants, err := repo.FindRoomParticipants(ctx, &indto.RoomParticipantParams{RoomID: msg.Room.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range participants {
+			audience[p.UserID] = true
+		}
+	} else if msg.Conversation != nil {
+		for _, p := range msg.Conversation.Participants {
+			audience[p.UserID] = true
+		}
+	} else {
+		audience[msg.Recipient.ID] = true
+	}
+
+	// value tell whether the user is only mentioned through @room
+	mentioned := map[int64]bool{}
+	if roomMention && (msg.Room != nil || msg.Conversation != nil) {
+		for userID := range audience {
+			mentioned[userID] = true
+		}
+	}
+
+	if len(usernames) != 0 {
+		users, err := repo.FindUsers(ctx, &indto.UserParams{Usernames: usernames})
+		if err != nil {
+			return nil, err
+		}
+
+		isPublicRoom := msg.Room != nil && msg.Room.Visibility == inconst.RoomVisibilityPublic
+		for _, u := range users {
+			if audience[u.ID] || isPublicRoom {
+				mentioned[u.ID] = false
+			}
+		}
+	}
+
+	delete(mentioned, msg.Sender.ID)
+
+	for userID, isRoomMention := range mentioned {
+		res = append(res, &model.Mention{UserID: userID, IsRoomMention: isRoomMention})
+	}
+
+	slices.SortFunc(res, func(a, b *model.Mention) int { return cmp.Compare(a.UserID, b.UserID) })
+
+	return
+}
+
+// notifyMentions push the message to every mentioned user regardless of the rooms their connections are subscribed to
+func notifyMentions(hub *LiveChatHub, mentions []*model.Mention, msg *indto.IncomingMessage) {
+	for _, m := range mentions {
+		hub.SendToUser(m.UserID, dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatMentionEvent,
+			Data:      &indto.MentionInfo{Message: msg, RoomMention: m.IsRoomMention},
+		})
+	}
+}
+
+// fetchMentions list a single page of messages mentioning the user, one extra row is queried to determine whether more page exists
+func fetchMentions(ctx context.Context, repo inrepo.Repository, userID int64, payload *dto.MentionListPayload) (res *indto.MentionListResponse, err error) {
+	if payload.Limit == 0 {
+		payload.Limit = defaultMentionLimit
+	} else if payload.Limit > maxMentionLimit {
+		payload.Limit = maxMentionLimit
+	}
+
+	mentions, err := repo.FindMentions(ctx, &indto.MentionParams{
+		UserID:       userID,
+		RoomID:       payload.RoomID,
+		Visibilities: []string{inconst.RoomVisibilityPublic},
+		BeforeID:     payload.BeforeID,
+		Limit:        payload.Limit + 1,
+	})
+	if err != nil {
+		return
+	}
+
+	res = &indto.MentionListResponse{Mentions: []*indto.MentionInfo{}}
+	if uint64(len(mentions)) > payload.Limit {
+		mentions = mentions[:payload.Limit]
+		res.HasMore = true
+	}
+
+	incoming := []*indto.IncomingMessage{}
+	for _, m := range mentions {
+		info := &indto.MentionInfo{
+			Message:     toIncomingMessages([]*model.ChatHistory{&m.ChatHistory})[0],
+			RoomMention: m.IsRoomMention,
+		}
+
+		res.Mentions = append(res.Mentions, info)
+		incoming = append(incoming, info.Message)
+	}
+
+	if err = loadAttachments(ctx, repo, incoming); err != nil {
+		return nil, err
+	}
+
+	if len(mentions) != 0 {
+		res.OldestID = mentions[len(mentions)-1].ID
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) sendMentionList(event *dto.LiveChatSocketEvent) {
+	payload := &dto.MentionListPayload{}
+	if data, ok := event.Data.(map[string]any); ok {
+		payload = structutil.MapToStruct[*dto.MentionListPayload](data)
+	}
+
+	res, err := fetchMentions(lc.ctx, lc.repo, lc.UserID, payload)
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch mentions")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch mentions",
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatMentionsEvent,
+		Data:      res,
+	}
+}
+
+func HandleListMentions(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		payload := &dto.MentionListPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		res, err := fetchMentions(ctx, params.Repo, sessionUser(c).ID, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to fetch mentions")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := []struct {
+		name      string
+		content   string
+		usernames []string
+		room      bool
+	}{
+		{name: "none", content: "hello there"},
+		{name: "single", content: "@alice hi", usernames: []string{"alice"}},
+		{name: "mid sentence", content: "ask @bob about it", usernames: []string{"bob"}},
+		{name: "trailing punctuation", content: "thanks @alice. and @bob-", usernames: []string{"alice", "bob"}},
+		{name: "inner punctuation kept", content: "cc @john.doe @jane_doe @a-b", usernames: []string{"john.doe", "jane_doe", "a-b"}},
+		{name: "after bracket", content: "(@alice) [@bob]", usernames: []string{"alice", "bob"}},
+		{name: "unique", content: "@alice @bob @alice", usernames: []string{"alice", "bob"}},
+		{name: "email", content: "mail alice@example.com"},
+		{name: "inside word", content: "foo@bar x.@baz _@qux"},
+		{name: "double at", content: "@@alice"},
+		{name: "bare at", content: "@ alone @. @-"},
+		{name: "unicode", content: "hai @ñandú", usernames: []string{"ñandú"}},
+		{name: "room", content: "@room heads up", room: true},
+		{name: "room with user", content: "@room and @alice", usernames: []string{"alice"}, room: true},
+		{name: "room is exact", content: "@roomy", usernames: []string{"roomy"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usernames, room := parseMentions(tc.content)
+			if !slices.Equal(usernames, tc.usernames) || room != tc.room {
+				t.Fatalf("expected %v room %v, got %v room %v", tc.usernames, tc.room, usernames, room)
+			}
+		})
+	}
+
+	t.Run("capped", func(t *testing.T) {
+		content := ""
+		for i := 0; i < maxMentionsPerMessage+10; i++ {
+			content += " @user" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		}
+
+		if usernames, _ := parseMentions(content); len(usernames) != maxMentionsPerMessage {
+			t.Fatalf("expected %d mentions, got %d", maxMentionsPerMessage, len(usernames))
+		}
+	})
+}
+
+func TestResolveMentions(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	carol := createTestUser(t, repo, "carol")
+	dave := createTestUser(t, repo, "dave")
+
+	public := createTestRoom(t, repo, "general", map[int64]string{
+		alice.ID: inconst.RoomRoleOwner,
+		bob.ID:   inconst.RoomRoleMember,
+		carol.ID: inconst.RoomRoleMember,
+	})
+
+	// visibility is only read from the struct, membership is shared with the public room
+	private := *public
+	private.Visibility = inconst.RoomVisibilityPrivate
+
+	conversation := &model.Conversation{Participants: []*model.ConversationParticipant{
+		{UserID: alice.ID}, {UserID: bob.ID}, {UserID: carol.ID},
+	}}
+
+	type mention struct {
+		userID int64
+		room   bool
+	}
+
+	cases := []struct {
+		name string
+		msg  *outgoingMessage
+		want []mention
+	}{
+		{
+			name: "no mention",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "hello"}},
+		},
+		{
+			name: "room member",
+			msg:  &outgoingMessage{Sender: alice, Room: &private, Envelope: dto.MessageEnvelope{Content: "@bob hi"}},
+			want: []mention{{userID: bob.ID}},
+		},
+		{
+			name: "outsider of private room",
+			msg:  &outgoingMessage{Sender: alice, Room: &private, Envelope: dto.MessageEnvelope{Content: "@bob @dave hi"}},
+			want: []mention{{userID: bob.ID}},
+		},
+		{
+			name: "outsider of public room",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "@dave join us"}},
+			want: []mention{{userID: dave.ID}},
+		},
+		{
+			name: "unknown user",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "@mallory hi"}},
+		},
+		{
+			name: "self",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "@alice note"}},
+		},
+		{
+			name: "room mention",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "@room lunch"}},
+			want: []mention{{userID: bob.ID, room: true}, {userID: carol.ID, room: true}},
+		},
+		{
+			name: "room mention along with direct mention",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Content: "@room lunch, @bob pay"}},
+			want: []mention{{userID: bob.ID}, {userID: carol.ID, room: true}},
+		},
+		{
+			name: "code snippet",
+			msg:  &outgoingMessage{Sender: alice, Room: public, Envelope: dto.MessageEnvelope{Type: inconst.MessageTypeCode, Content: "@bob\nfunc x()"}},
+		},
+		{
+			name: "conversation",
+			msg:  &outgoingMessage{Sender: bob, Conversation: conversation, Envelope: dto.MessageEnvelope{Content: "@alice @dave"}},
+			want: []mention{{userID: alice.ID}},
+		},
+		{
+			name: "conversation room mention",
+			msg:  &outgoingMessage{Sender: bob, Conversation: conversation, Envelope: dto.MessageEnvelope{Content: "@room"}},
+			want: []mention{{userID: alice.ID, room: true}, {userID: carol.ID, room: true}},
+		},
+		{
+			name: "direct message",
+			msg:  &outgoingMessage{Sender: alice, Recipient: bob, Envelope: dto.MessageEnvelope{Content: "@bob @carol"}},
+			want: []mention{{userID: bob.ID}},
+		},
+		{
+			name: "direct message room mention",
+			msg:  &outgoingMessage{Sender: alice, Recipient: bob, Envelope: dto.MessageEnvelope{Content: "@room"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := resolveMentions(ctx, repo, tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []mention{}
+			for _, m := range res {
+				got = append(got, mention{userID: m.UserID, room: m.IsRoomMention})
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMentionsOverSocket(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	// carol isn't subscribed to the room yet still notified
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "@carol come join"})
+	notice := decodeEvent[*indto.MentionInfo](t, carol.expect(inconst.LiveChatMentionEvent))
+	if notice.RoomMention || notice.Message.Content != "@carol come join" || notice.Message.RoomID != room.ID {
+		t.Fatalf("unexpected mention %+v", notice)
+	}
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "@room first"})
+	first := decodeEvent[*indto.MentionInfo](t, bob.expect(inconst.LiveChatMentionEvent))
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "@bob second"})
+	second := decodeEvent[*indto.MentionInfo](t, bob.expect(inconst.LiveChatMentionEvent))
+	if !first.RoomMention || second.RoomMention {
+		t.Fatalf("expected only the first to be room mention, got %v %v", first.RoomMention, second.RoomMention)
+	}
+
+	// newest first, paged by before_id
+	bob.send(inconst.LiveChatListMentionEvent, map[string]any{"limit": 1})
+	page := decodeEvent[*indto.MentionListResponse](t, bob.expect(inconst.LiveChatMentionsEvent))
+	if len(page.Mentions) != 1 || page.Mentions[0].Message.ID != second.Message.ID || !page.HasMore || page.OldestID != second.Message.ID {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+
+	bob.send(inconst.LiveChatListMentionEvent, map[string]any{"limit": 1, "before_id": page.OldestID})
+	page = decodeEvent[*indto.MentionListResponse](t, bob.expect(inconst.LiveChatMentionsEvent))
+	if len(page.Mentions) != 1 || page.Mentions[0].Message.ID != first.Message.ID || !page.Mentions[0].RoomMention || page.HasMore {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	// alice is never notified of her own mention
+	alice.send(inconst.LiveChatListMentionEvent, nil)
+	page = decodeEvent[*indto.MentionListResponse](t, alice.expect(inconst.LiveChatMentionsEvent))
+	if len(page.Mentions) != 0 || page.HasMore {
+		t.Fatalf("sender should have no mention, got %+v", page)
+	}
+}
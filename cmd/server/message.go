@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+type outgoingMessage struct {
+	Sender        *model.User
+	Room          *model.ChatRoom     // nil for direct message
+	Recipient     *model.User         // nil for room message
+	Conversation  *model.Conversation // set instead of Recipient for group conversation, participants must be loaded
+	ParentID      int64               // set for thread reply
+	Envelope      dto.MessageEnvelope // validated and normalized by postMessage
+	AttachmentIDs []int64             // unsent uploads of the sender
+}
+
+// postMessage persist message then dispatch it to room member or DM participants through the hub
+func postMessage(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, msg *outgoingMessage) (res *indto.IncomingMessage, err error) {
+	if err = validateEnvelope(&msg.Envelope, len(msg.AttachmentIDs) != 0); err != nil {
+		return nil, err
+	}
+
+	meta, err := encodeMessageMeta(&msg.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	res = &indto.IncomingMessage{
+		SenderID:   msg.Sender.ID,
+		SenderName: msg.Sender.Username,
+		Type:       msg.Envelope.Type,
+		Content:    msg.Envelope.Content,
+		Language:   msg.Envelope.Language,
+		Card:       msg.Envelope.Card,
+		IsDM:       msg.Room == nil,
+		ParentID:   msg.ParentID,
+		CreatedAt:  time.Now(),
+	}
+
+	history := &model.ChatHistory{
+		SenderID:    msg.Sender.ID,
+		Message:     msg.Envelope.Content,
+		MessageType: msg.Envelope.Type,
+		MessageMeta: meta,
+		ParentID:    msg.ParentID,
+		CreatedAt:   res.CreatedAt,
+	}
+
+	if msg.Room != nil {
+		if err = ensureCanPost(ctx, repo, msg.Room.ID, msg.Sender.ID); err != nil {
+			return nil, err
+		}
+
+		res.RoomID, res.RoomName = msg.Room.ID, msg.Room.RoomName
+		history.RoomID = msg.Room.ID
+	} else if msg.Conversation != nil {
+		res.ConversationID = msg.Conversation.ID
+		history.ConversationID = msg.Conversation.ID
+	} else {
+		res.RecipientID = msg.Recipient.ID
+		history.RecipientID = msg.Recipient.ID
+	}
+
+	mentions, err := resolveMentions(ctx, repo, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// direct message is tracked until the recipient received it, the hub will mark it once delivered live
+	recipientIDs := []int64{}
+	if msg.Recipient != nil {
+		recipientIDs = append(recipientIDs, msg.Recipient.ID)
+	} else if msg.Conversation != nil {
+		for _, p := range msg.Conversation.Participants {
+			if p.UserID != msg.Sender.ID {
+				recipientIDs = append(recipientIDs, p.UserID)
+			}
+		}
+	}
+
+	// message is persisted along with everything refering to it at once, nothing is dispatched until it is committed
+	err = repo.RunInTx(ctx, func(tx inrepo.Repository) (err error) {
+		attachments := []*model.Attachment{}
+		if len(msg.AttachmentIDs) != 0 {
+			// checked within the transaction so the same upload is never sent with two messages
+			attachments, err = findUsableAttachments(ctx, tx, msg.Sender.ID, msg.AttachmentIDs)
+			if err != nil {
+				return
+			}
+		}
+
+		res.ID, err = tx.InsertChatHistory(ctx, history)
+		if err != nil {
+			return
+		}
+
+		if len(attachments) != 0 {
+			err = tx.LinkAttachments(ctx, &indto.AttachmentParams{IDs: msg.AttachmentIDs, UploaderID: msg.Sender.ID, MessageID: res.ID})
+			if err != nil {
+				return
+			}
+
+			res.Attachments = toAttachmentInfos(attachments)
+		}
+
+		for _, recipientID := range recipientIDs {
+			err = tx.InsertMessageDelivery(ctx, &model.MessageDelivery{
+				MessageID:   res.ID,
+				RecipientID: recipientID,
+				CreatedAt:   res.CreatedAt,
+			})
+			if err != nil {
+				return
+			}
+		}
+
+		for _, m := range mentions {
+			m.MessageID, m.CreatedAt = res.ID, res.CreatedAt
+		}
+
+		return tx.InsertMentions(ctx, mentions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event := dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatIncomingMsgEvent,
+		Data:      res,
+	}
+
+	// sending a message implicitly end sender typing state
+	typing := &dto.LiveChatTypingEvent{UserID: msg.Sender.ID, Username: msg.Sender.Username}
+
+	if msg.Room != nil {
+		hub.broadcast <- dto.LiveChatBroadcastEvent{
+			Room:  msg.Room.ID,
+			Event: event,
+		}
+		typing.RoomID = msg.Room.ID
+	} else if msg.Conversation != nil {
+		hub.msgChan <- &dto.LiveChatSocketRequest{
+			MessageID:      res.ID,
+			SenderID:       msg.Sender.ID,
+			RecipientIDs:   recipientIDs,
+			ConversationID: msg.Conversation.ID,
+			Event:          event,
+		}
+	} else {
+		hub.msgChan <- &dto.LiveChatSocketRequest{
+			MessageID:   res.ID,
+			SenderID:    msg.Sender.ID,
+			RecipientID: msg.Recipient.ID,
+			Event:       event,
+		}
+		typing.PeerID = msg.Recipient.ID
+	}
+
+	// typing indicator is only tracked for rooms and DM peers
+	if msg.Conversation == nil {
+		hub.typing <- typing
+	}
+
+	// notified after the message itself so client already has it once the mention arrive
+	notifyMentions(hub, mentions, res)
+
+	return
+}
+
+// postSystemMessages persist server generated notices into the room then broadcast them once committed, system message has no sender.
+// apply run within the same transaction so the notices are only kept along with the change they announce
+func postSystemMessages(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, room *model.ChatRoom, contents []string, apply func(inrepo.Repository) error) (res []*indto.IncomingMessage, err error) {
+	now := time.Now()
+
+	err = repo.RunInTx(ctx, func(tx inrepo.Repository) (err error) {
+		if apply != nil {
+			if err = apply(tx); err != nil {
+				return
+			}
+		}
+
+		res = []*indto.IncomingMessage{}
+		for _, content := range contents {
+			msg := &indto.IncomingMessage{
+				RoomID:    room.ID,
+				RoomName:  room.RoomName,
+				Type:      inconst.MessageTypeSystem,
+				Content:   content,
+				IsSystem:  true,
+				CreatedAt: now,
+			}
+
+			msg.ID, err = tx.InsertChatHistory(ctx, &model.ChatHistory{
+				RoomID:      room.ID,
+				Message:     content,
+				MessageType: inconst.MessageTypeSystem,
+				CreatedAt:   now,
+			})
+			if err != nil {
+				return
+			}
+
+			res = append(res, msg)
+		}
+
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range res {
+		hub.broadcast <- dto.LiveChatBroadcastEvent{
+			Room: room.ID,
+			Event: dto.LiveChatSocketEvent{
+				EventName: inconst.LiveChatIncomingMsgEvent,
+				Data:      msg,
+			},
+		}
+	}
+
+	return
+}
+
+// findAccessibleMessage fetch message by id and ensure the user is either member of its room or participant of the DM or conversation
+func findAccessibleMessage(ctx context.Context, repo inrepo.Repository, messageID int64, userID int64) (msg *model.ChatHistory, err error) {
+	msg, err = repo.FindChatMessage(ctx, &indto.ChatHistoryParams{ID: messageID})
+	if err != nil {
+		return nil, err
+	} else if msg == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	if msg.RoomID != 0 {
+		participant, err := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: msg.RoomID, UserID: userID})
+		if err != nil {
+			return nil, err
+		} else if participant == nil {
+			return nil, errs.ErrForbidden
+		}
+	} else if msg.ConversationID != 0 {
+		if _, err = findConversation(ctx, repo, msg.ConversationID, userID); err != nil {
+			return nil, err
+		}
+	} else if msg.SenderID != userID && msg.RecipientID != userID {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
+
+// publishMessageEvent dispatch event about an existing message to the same audience its original were sent to
+func publishMessageEvent(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, msg *model.ChatHistory, event dto.LiveChatSocketEvent) (err error) {
+	if msg.RoomID != 0 {
+		hub.broadcast <- dto.LiveChatBroadcastEvent{
+			Room:  msg.RoomID,
+			Event: event,
+		}
+		return
+	}
+
+	if msg.ConversationID != 0 {
+		participants, err := repo.FindConversationParticipants(ctx, &indto.ConversationParams{ID: msg.ConversationID})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range participants {
+			hub.SendToUser(p.UserID, event)
+		}
+		return nil
+	}
+
+	hub.SendToUser(msg.SenderID, event)
+	if msg.RecipientID != msg.SenderID {
+		hub.SendToUser(msg.RecipientID, event)
+	}
+
+	return
+}
+
+// sendPostError report postMessage failure back to the client
+func (lc *LiveChatSocketMiddleware) sendPostError(err error) {
+	msg := "failed to save message"
+	switch {
+	case errors.Is(err, errs.ErrMuted):
+		msg = "muted in the room"
+	case errors.Is(err, errs.ErrInvalidMsg):
+		msg = err.Error()
+	case errors.Is(err, errs.ErrBadRequest):
+		msg = "invalid or already sent attachment"
+	case errors.Is(err, errs.ErrForbidden):
+		msg = "not joined to the room"
+	default:
+		lc.logger.Error().Err(err).Msg("failed to save message")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      msg,
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// failingMentionRepo fail the last write of postMessage once everything else has been written
+type failingMentionRepo struct {
+	inrepo.Repository
+}
+
+func (r *failingMentionRepo) RunInTx(ctx context.Context, fn func(inrepo.Repository) error) error {
+	return r.Repository.RunInTx(ctx, func(tx inrepo.Repository) error {
+		return fn(&failingMentionRepo{Repository: tx})
+	})
+}
+
+func (r *failingMentionRepo) InsertMentions(context.Context, []*model.Mention) error {
+	return errors.New("disk is full")
+}
+
+func TestPostMessageAtomic(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := testContext()
+	alice := createTestUser(t, srv.repo, "alice")
+	bobConn := srv.connect(t, "bob")
+	bob, _ := srv.repo.FindUser(ctx, &indto.UserParams{Username: "bob"})
+
+	attachmentID, err := srv.repo.InsertAttachment(ctx, &model.Attachment{
+		UploaderID: alice.ID, FileName: "a.png", ContentType: "image/png", Size: 1, StorageKey: "key", CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(repo inrepo.Repository, content string) (*indto.IncomingMessage, error) {
+		return postMessage(ctx, repo, srv.hub, &outgoingMessage{
+			Sender:        alice,
+			Recipient:     bob,
+			Envelope:      dto.MessageEnvelope{Content: content},
+			AttachmentIDs: []int64{attachmentID},
+		})
+	}
+
+	if _, err = send(&failingMentionRepo{Repository: srv.repo}, "@bob first"); err == nil {
+		t.Fatal("expected failed post")
+	}
+
+	history, err := srv.repo.FindChatHistory(ctx, &indto.ChatHistoryParams{UserID: alice.ID, PeerID: bob.ID, IsDM: true, Limit: 10})
+	if err != nil || len(history) != 0 {
+		t.Fatalf("failed post must not leave the message behind, got %d err %v", len(history), err)
+	}
+
+	attachment, err := srv.repo.FindAttachment(ctx, &indto.AttachmentParams{ID: attachmentID})
+	if err != nil || attachment.MessageID != 0 {
+		t.Fatalf("failed post must not consume the attachment, got %+v err %v", attachment, err)
+	}
+
+	pending, err := srv.repo.FindUndeliveredMessages(ctx, &indto.MessageDeliveryParams{RecipientID: bob.ID})
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("failed post must not leave pending delivery, got %d err %v", len(pending), err)
+	}
+
+	sent, err := send(srv.repo, "@bob second")
+	if err != nil {
+		t.Fatalf("failed to post: %v", err)
+	}
+
+	// nothing from the failed post is ever dispatched
+	incoming := decodeEvent[*indto.IncomingMessage](t, bobConn.expect(inconst.LiveChatIncomingMsgEvent))
+	if incoming.ID != sent.ID || incoming.Content != "@bob second" || len(incoming.Attachments) != 1 {
+		t.Fatalf("expected the second message with its attachment, got %+v", incoming)
+	}
+
+	mention := decodeEvent[*indto.MentionInfo](t, bobConn.expect(inconst.LiveChatMentionEvent))
+	if mention.Message.ID != sent.ID {
+		t.Fatalf("expected mention of the second message, got %d", mention.Message.ID)
+	}
+
+	if _, err = send(srv.repo, "third"); !errors.Is(err, errs.ErrBadRequest) {
+		t.Fatalf("attachment must not be sent twice, got %v", err)
+	}
+}
+
+func TestRoomMessageWireFormat(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	before := time.Now()
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "hello"})
+
+	// every member, the sender included, receive the server assigned id and timestamp
+	live := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent))
+	echo := decodeEvent[*indto.IncomingMessage](t, alice.expect(inconst.LiveChatIncomingMsgEvent))
+	if live.ID == 0 || live.ID != echo.ID || !live.CreatedAt.Equal(echo.CreatedAt) {
+		t.Fatalf("members disagree on the message: %+v %+v", live, echo)
+	}
+
+	if live.CreatedAt.Before(before.Add(-time.Second)) || live.CreatedAt.After(time.Now()) {
+		t.Fatalf("timestamp should be assigned by the server on send, got %v", live.CreatedAt)
+	}
+
+	if live.RoomID != room.ID || live.RoomName != "general" || live.SenderName != "alice" || live.Type != inconst.MessageTypeText {
+		t.Fatalf("unexpected message %+v", live)
+	}
+
+	// replayed history carry the same id and timestamp as the live message
+	bob.send(inconst.LiveChatRoomLogEvent, "general")
+	history := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatMsgLogEvent))
+	if len(history.Messages) != 1 || history.Messages[0].ID != live.ID || !history.Messages[0].CreatedAt.Equal(live.CreatedAt) {
+		t.Fatalf("history differ from live message: %+v", history.Messages)
+	}
+}
@@ -3,6 +3,9 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -23,7 +26,9 @@ const (
 	writeWait  = 10 * time.Second
 	pongWait   = 60 * time.Second
 	pingPeriod = (pongWait * 9) / 10
-	maxMsgSize = 1024
+	maxMsgSize = 128 << 10
+	// maxFrameSize close the connection outright, larger message up to this size is drained and rejected with an error
+	maxFrameSize = 1 << 20
 )
 
 type LiveChatSocketParams struct {
@@ -41,16 +46,19 @@ var (
 )
 
 type LiveChatSocketMiddleware struct {
-	UserID       int64
-	username     string
-	ctx          context.Context
-	hub          *LiveChatHub
-	conn         *websocket.Conn
-	logger       *zerolog.Logger
-	repo         inrepo.Repository
-	in           chan dto.LiveChatSocketEvent
+	UserID    int64
+	username  string
+	sessionID string
+	ctx       context.Context
+	hub       *LiveChatHub
+	conn      *websocket.Conn
+	logger    *zerolog.Logger
+	repo      inrepo.Repository
+	in        chan dto.LiveChatSocketEvent
+	dedupe    *directDedupe
+
+	// activeRoomID is the room joined last on this connection, plain text room message and bare leave target it
 	activeRoomID int64
-	isDM         bool
 }
 
 func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
@@ -63,6 +71,8 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 			params.Logger.Error().Err(err).Msg("failed to upgrade connection to WS")
 			return err
 		}
+		// login message is tiny, the reader raise the limit once authenticated
+		ws.SetReadLimit(maxMsgSize)
 
 		client := &LiveChatSocketMiddleware{
 			ctx:    ctx,
@@ -71,10 +81,12 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 			logger: params.Logger,
 			repo:   params.Repo,
 			in:     make(chan dto.LiveChatSocketEvent, 256),
+			dedupe: newDirectDedupe(),
 		}
 
 		authenticated := false
 		msg := &dto.LiveChatSocketEvent{}
+		sessionMeta := &dto.AuthSessionResponse{}
 
 		sendMessage := func(msg any) (err error) {
 			if v, ok := msg.(error); ok {
@@ -99,6 +111,23 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 			return
 		}
 
+		// resume session directly when token is supplied on the upgrade request
+		if token := extractToken(c.Request()); token != "" {
+			userMeta, session, err := authenticateToken(ctx, params.Repo, token)
+			if err != nil {
+				params.Logger.Error().Err(err).Msg("failed to validate session token")
+				sendMessage(errs.ErrInvalidToken)
+			} else {
+				client.UserID = userMeta.ID
+				client.username = userMeta.Username
+				client.sessionID = session.ID
+				sessionMeta = &dto.AuthSessionResponse{ExpiresAt: session.ExpiresAt, UserID: userMeta.ID, Username: userMeta.Username}
+				authenticated = true
+
+				params.Logger.Info().Str("username", userMeta.Username).Msg("user resumed session")
+			}
+		}
+
 		for !authenticated {
 			if err := ws.ReadJSON(msg); err != nil {
 				params.Logger.Error().Err(err).Msg("failed to parse initial msg")
@@ -109,36 +138,61 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 
 			switch msg.EventName {
 			case inconst.LiveChatAuthLoginEvent:
-				cred := structutil.MapToStruct[*dto.AuthLoginPayload](msg.Data.(map[string]any))
+				data, _ := msg.Data.(map[string]any)
+				cred := structutil.MapToStruct[*dto.AuthLoginPayload](data)
 				if cred == nil {
 					client.logger.Error().Err(err).Msg("failed to parse msg body")
+					sendMessage(errs.ErrBrokenUserReq)
 					continue
 				}
 
-				userMeta, err := params.Repo.FindUser(ctx, &indto.UserParams{Username: cred.Username})
-				if err != nil || userMeta == nil {
-					params.Logger.Error().Err(err).Msg("failed to validate user")
+				userMeta, err := verifyCredential(ctx, params.Repo, cred)
+				if err != nil {
+					params.Logger.Error().Err(err).Msg("failed to validate credentials")
 
 					sendMessage(errs.ErrInvalidCred)
 					continue
 				}
 
-				if err = bcrypt.CompareHashAndPassword([]byte(userMeta.Password), []byte(cred.Password)); err != nil {
-					params.Logger.Error().Err(err).Msg("failed to validate credentials")
+				session, sessionID, err := issueSession(ctx, params.Repo, userMeta)
+				if err != nil {
+					params.Logger.Error().Err(err).Msg("failed to issue session")
 
-					sendMessage(errs.ErrInvalidCred)
+					sendMessage(errs.ErrUnknown)
 					continue
 				}
 
 				client.UserID = userMeta.ID
 				client.username = userMeta.Username
+				client.sessionID = sessionID
+				sessionMeta = session
 				authenticated = true
 
 				params.Logger.Info().Str("username", userMeta.Username).Msg("user logged in")
+			case inconst.LiveChatAuthTokenEvent:
+				token, _ := msg.Data.(string)
+
+				userMeta, session, err := authenticateToken(ctx, params.Repo, token)
+				if err != nil {
+					params.Logger.Error().Err(err).Msg("failed to validate session token")
+
+					sendMessage(errs.ErrInvalidToken)
+					continue
+				}
+
+				client.UserID = userMeta.ID
+				client.username = userMeta.Username
+				client.sessionID = session.ID
+				sessionMeta = &dto.AuthSessionResponse{ExpiresAt: session.ExpiresAt, UserID: userMeta.ID, Username: userMeta.Username}
+				authenticated = true
+
+				params.Logger.Info().Str("username", userMeta.Username).Msg("user resumed session")
 			case inconst.LiveChatAuthSignupEvent:
-				cred := structutil.MapToStruct[*dto.AuthLoginPayload](msg.Data.(map[string]any))
+				data, _ := msg.Data.(map[string]any)
+				cred := structutil.MapToStruct[*dto.AuthLoginPayload](data)
 				if cred == nil {
 					client.logger.Error().Err(err).Msg("failed to parse msg body")
+					sendMessage(errs.ErrBrokenUserReq)
 					continue
 				}
 
@@ -187,7 +241,7 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 
 		bJson, err := json.Marshal(&dto.LiveChatSocketEvent{
 			EventName: inconst.LiveChatAuthAckEvent,
-			Data:      nil,
+			Data:      sessionMeta,
 		})
 		if err != nil {
 			params.Logger.Error().Err(err).Msg("failed to marshal msg")
@@ -200,27 +254,64 @@ func HandleLiveChatSocket(params *LiveChatSocketParams) echo.HandlerFunc {
 			return
 		}
 
-		go client.Reader()
+		// writer is started first since queued message could exceed the outbound buffer
 		go client.Writer()
 
+		client.rejoinRooms()
+		client.flushPendingMessages()
+		client.sendUnreadCounts()
+
+		go client.Reader()
+
 		return
 	}
 }
 
+// user return usermeta of the authenticated connection
+func (lc *LiveChatSocketMiddleware) user() *model.User {
+	return &model.User{ID: lc.UserID, Username: lc.username}
+}
+
+var errMsgTooLarge = errors.New("message too large")
+
+// readEvent decode the next message, message over maxMsgSize is reported as errMsgTooLarge and its remainder is discarded on the next read
+func (lc *LiveChatSocketMiddleware) readEvent() (event *dto.LiveChatSocketEvent, err error) {
+	_, r, err := lc.conn.NextReader()
+	if err != nil {
+		return
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, maxMsgSize+1))
+	if err != nil {
+		return
+	} else if len(b) > maxMsgSize {
+		return nil, errMsgTooLarge
+	}
+
+	event = &dto.LiveChatSocketEvent{}
+	err = json.Unmarshal(b, event)
+	return
+}
+
 func (lc *LiveChatSocketMiddleware) Reader() {
 	defer func() {
 		lc.hub.unregister <- lc
 		lc.conn.Close()
 	}()
 
-	lc.conn.SetReadLimit(maxMsgSize)
+	lc.conn.SetReadLimit(maxFrameSize)
 	lc.conn.SetReadDeadline(time.Now().Add(pongWait))
 	lc.conn.SetPongHandler(func(string) error { lc.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 
 	for {
-		event := &dto.LiveChatSocketEvent{}
-		err := lc.conn.ReadJSON(event)
-		if err != nil {
+		event, err := lc.readEvent()
+		if errors.Is(err, errMsgTooLarge) {
+			lc.in <- dto.LiveChatSocketEvent{
+				EventName: inconst.LiveChatErrorMsgEvent,
+				Data:      fmt.Sprintf("message exceed %d bytes", maxMsgSize),
+			}
+			continue
+		} else if err != nil {
 			lc.logger.Error().Err(err).Msg("failed to parse msg")
 			lc.in <- dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatErrorMsgEvent,
@@ -230,128 +321,185 @@ func (lc *LiveChatSocketMiddleware) Reader() {
 		}
 
 		switch event.EventName {
-		case inconst.LiveChatCreateRoomEvent:
-			if exists, err := lc.repo.FindRoom(lc.ctx, &indto.ChatRoomParams{RoomName: event.Data.(string)}); err != nil {
-				lc.logger.Error().Err(err).Msg("failed to fetch room data")
-				lc.in <- dto.LiveChatSocketEvent{
-					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to fetch room data",
-				}
-				continue
-			} else if exists != nil {
-				lc.logger.Error().Err(err).Msg("room already exists")
+		case inconst.LiveChatAuthLogoutEvent:
+			if err := lc.repo.RevokeUserSession(lc.ctx, &indto.UserSessionParams{ID: lc.sessionID}); err != nil {
+				lc.logger.Error().Err(err).Msg("failed to revoke session")
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "room already exists",
+					Data:      "failed to revoke session",
 				}
 				continue
 			}
 
-			if err = lc.repo.CreateRoom(lc.ctx, &model.ChatRoom{RoomName: event.Data.(string)}); err != nil {
-				lc.logger.Error().Err(err).Msg("failed to create room data")
+			lc.logger.Info().Str("username", lc.username).Msg("user logged out")
+			lc.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "logged out"), time.Now().Add(writeWait))
+			return
+		case inconst.LiveChatCreateRoomEvent:
+			createPayload := &dto.CreateRoomPayload{}
+			switch v := event.Data.(type) {
+			case string:
+				createPayload.RoomName = v
+			case map[string]any:
+				createPayload = structutil.MapToStruct[*dto.CreateRoomPayload](v)
+			}
+
+			roomMeta, err := createRoom(lc.ctx, lc.repo, lc.hub, lc.user(), createPayload)
+			if err != nil {
+				msg := "failed to create room data"
+				switch {
+				case errors.Is(err, errs.ErrBadRequest):
+					msg = "invalid room name or visibility"
+				case errors.Is(err, errs.ErrRoomExisted):
+					msg = "room already exists"
+				default:
+					lc.logger.Error().Err(err).Msg("failed to create room data")
+				}
+
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to create room data",
+					Data:      msg,
 				}
 				continue
 			}
 
-			lc.in <- dto.LiveChatSocketEvent{
+			lc.hub.SendToUser(lc.UserID, dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatCreatedEvent,
-			}
+				Data:      toRoomInfos([]*model.ChatRoom{roomMeta})[0],
+			})
 			continue
-		case inconst.LiveChatJoinRoomEvent:
-			roomMeta, err := lc.repo.FindRoom(lc.ctx, &indto.ChatRoomParams{RoomName: event.Data.(string)})
+		case inconst.LiveChatJoinRoomEvent, inconst.LiveChatAcceptInviteEvent:
+			joinPayload := &dto.JoinRoomPayload{}
+			switch v := event.Data.(type) {
+			case string:
+				joinPayload.RoomName = v
+			case map[string]any:
+				joinPayload = structutil.MapToStruct[*dto.JoinRoomPayload](v)
+			}
+
+			roomMeta, err := joinRoom(lc.ctx, lc.repo, lc.hub, lc.user(), &indto.ChatRoomParams{
+				ID:         joinPayload.RoomID,
+				RoomName:   joinPayload.RoomName,
+				InviteCode: joinPayload.InviteCode,
+			})
 			if err != nil {
-				lc.logger.Error().Err(err).Msg("failed to fetch room data")
-				lc.in <- dto.LiveChatSocketEvent{
-					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to fetch room data",
+				msg := "failed to save room membership"
+				switch {
+				case errors.Is(err, errs.ErrBadRequest):
+					msg = "room is not specified"
+				case errors.Is(err, errs.ErrNotFound):
+					msg = "room doesnt exists"
+				case errors.Is(err, errs.ErrBanned):
+					msg = "banned from the room"
+				case errors.Is(err, errs.ErrForbidden):
+					msg = "invite required to join the room"
+				default:
+					lc.logger.Error().Err(err).Msg("failed to save room membership")
 				}
-				continue
-			} else if roomMeta == nil {
-				lc.logger.Error().Err(err).Msg("room doesnt exists")
+
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "room doesnt exists",
+					Data:      msg,
 				}
 				continue
 			}
 
-			lc.hub.JoinRoom(roomMeta.ID, lc)
-			lc.in <- dto.LiveChatSocketEvent{
+			lc.hub.SendToUser(lc.UserID, dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatJoinedEvent,
-			}
+				Data:      toRoomInfos([]*model.ChatRoom{roomMeta})[0],
+			})
 			lc.activeRoomID = roomMeta.ID
 
 			continue
 		case inconst.LiveChatLeaveRoomEvent:
-			roomID := lc.activeRoomID
+			leavePayload := &dto.LeaveRoomPayload{}
+			switch v := event.Data.(type) {
+			case string:
+				leavePayload.RoomName = v
+			case map[string]any:
+				leavePayload = structutil.MapToStruct[*dto.LeaveRoomPayload](v)
+			case nil:
+				leavePayload.RoomID = lc.activeRoomID
+			}
 
-			roomMeta, err := lc.repo.FindRoom(lc.ctx, &indto.ChatRoomParams{ID: roomID})
-			if err != nil {
-				lc.logger.Error().Err(err).Msg("failed to fetch room data")
-				lc.in <- dto.LiveChatSocketEvent{
-					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to fetch room data",
-				}
+			roomMeta := lc.findJoinedRoom(&indto.ChatRoomParams{ID: leavePayload.RoomID, RoomName: leavePayload.RoomName})
+			if roomMeta == nil {
 				continue
-			} else if roomMeta == nil {
-				lc.logger.Error().Err(err).Msg("room doesnt exists")
+			}
+
+			err := lc.repo.DeleteRoomParticipant(lc.ctx, &indto.RoomParticipantParams{RoomID: roomMeta.ID, UserID: lc.UserID})
+			if err != nil {
+				lc.logger.Error().Err(err).Msg("failed to remove room membership")
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "room doesnt exists",
+					Data:      "failed to remove room membership",
 				}
 				continue
 			}
 
-			lc.hub.LeaveRoom(roomMeta.ID, lc)
-			lc.in <- dto.LiveChatSocketEvent{
+			lc.hub.LeaveUserRoom(roomMeta.ID, lc.UserID)
+			lc.hub.SendToUser(lc.UserID, dto.LiveChatSocketEvent{
 				EventName: inconst.LiveChatLeftEvent,
+				Data:      &indto.RoomInfo{ID: roomMeta.ID, RoomName: roomMeta.RoomName},
+			})
+			if lc.activeRoomID == roomMeta.ID {
+				lc.activeRoomID = 0
 			}
-			lc.activeRoomID = 0
 
 			continue
+		case inconst.LiveChatListRoomEvent:
+			lc.sendRoomList()
+		case inconst.LiveChatDirectoryEvent:
+			lc.sendRoomDirectory(event)
 		case inconst.LiveChatSendRoomMsgEvent:
-			incomingMessage := &indto.IncomingMessage{
-				SenderID:   lc.UserID,
-				SenderName: lc.username,
-				Content:    event.Data.(string),
-				IsDM:       false,
-			}
-
-			err := lc.repo.InsertChatHistory(lc.ctx, &model.ChatHistory{
-				RoomID:   lc.activeRoomID,
-				SenderID: lc.UserID,
-				Message:  event.Data.(string),
-			})
-			if err != nil {
-				lc.logger.Error().Err(err).Msg("failed to save message")
+			roomPayload := &dto.ChatRoomPayload{}
+			switch v := event.Data.(type) {
+			case string:
+				// plain text into the active room, as sent before the message envelope existed
+				if lc.activeRoomID == 0 {
+					lc.in <- dto.LiveChatSocketEvent{
+						EventName: inconst.LiveChatErrorMsgEvent,
+						Data:      "not joined to any room",
+					}
+					continue
+				}
+				roomPayload.RoomID, roomPayload.Content = lc.activeRoomID, v
+			case map[string]any:
+				roomPayload = structutil.MapToStruct[*dto.ChatRoomPayload](v)
+			default:
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to save message",
+					Data:      "invalid room message payload",
 				}
 				continue
 			}
 
-			lc.hub.broadcast <- dto.LiveChatBroadcastEvent{
-				Room: lc.activeRoomID,
-				Event: dto.LiveChatSocketEvent{
-					EventName: inconst.LiveChatIncomingMsgEvent,
-					Data:      incomingMessage,
-				},
+			roomMeta := lc.findJoinedRoom(&indto.ChatRoomParams{ID: roomPayload.RoomID, RoomName: roomPayload.RoomName})
+			if roomMeta == nil {
+				continue
 			}
-		case inconst.LiveChatSendDirectMsgEvent:
-			payload := structutil.MapToStruct[*dto.ChatDMPayload](event.Data.(map[string]any))
 
-			incomingMessage := &indto.IncomingMessage{
-				SenderID:   lc.UserID,
-				SenderName: lc.username,
-				Content:    payload.Content,
-				IsDM:       true,
+			_, err := postMessage(lc.ctx, lc.repo, lc.hub, &outgoingMessage{
+				Sender:        lc.user(),
+				Room:          roomMeta,
+				Envelope:      roomPayload.MessageEnvelope,
+				AttachmentIDs: roomPayload.AttachmentIDs,
+			})
+			if err != nil {
+				lc.sendPostError(err)
+				continue
 			}
+		case inconst.LiveChatSendDirectMsgEvent:
+			payload, ok := event.Data.(map[string]any)
+			if !ok {
+				lc.in <- dto.LiveChatSocketEvent{
+					EventName: inconst.LiveChatErrorMsgEvent,
+					Data:      "invalid direct message payload",
+				}
+				continue
+			}
+			dmPayload := structutil.MapToStruct[*dto.ChatDMPayload](payload)
 
-			recipientMeta, err := lc.repo.FindUser(lc.ctx, &indto.UserParams{Username: payload.RecipientUsername})
+			recipientMeta, err := lc.repo.FindUser(lc.ctx, &indto.UserParams{Username: dmPayload.RecipientUsername})
 			if err != nil {
 				lc.logger.Error().Err(err).Msg("failed to fetch recipient meta")
 				lc.in <- dto.LiveChatSocketEvent{
@@ -360,7 +508,6 @@ func (lc *LiveChatSocketMiddleware) Reader() {
 				}
 				continue
 			}
-			incomingMessage.RecipientID = recipientMeta.ID
 
 			if recipientMeta == nil {
 				lc.logger.Error().Err(err).Msg("recipient doesnt existed")
@@ -371,76 +518,106 @@ func (lc *LiveChatSocketMiddleware) Reader() {
 				continue
 			}
 
-			err = lc.repo.InsertChatHistory(lc.ctx, &model.ChatHistory{
-				SenderID:    lc.UserID,
-				RecipientID: recipientMeta.ID,
-				Message:     payload.Content,
+			_, err = postMessage(lc.ctx, lc.repo, lc.hub, &outgoingMessage{
+				Sender:        lc.user(),
+				Recipient:     recipientMeta,
+				Envelope:      dmPayload.MessageEnvelope,
+				AttachmentIDs: dmPayload.AttachmentIDs,
 			})
 			if err != nil {
-				lc.logger.Error().Err(err).Msg("failed to save message")
+				lc.sendPostError(err)
+				continue
+			}
+		case inconst.LiveChatSendGroupMsgEvent:
+			lc.handleGroupMessage(event)
+		case inconst.LiveChatGroupLogEvent:
+			lc.sendConversationLog(parseChatLogPayload(event.Data))
+		case inconst.LiveChatListGroupEvent:
+			lc.sendConversationList()
+		case inconst.LiveChatAckDeliveredEvent:
+			lc.handleReceipt(event, inconst.DeliveryStatusDelivered)
+		case inconst.LiveChatAckReadEvent:
+			lc.handleReceipt(event, inconst.DeliveryStatusRead)
+		case inconst.LiveChatUnreadEvent:
+			lc.sendUnreadCounts()
+		case inconst.LiveChatSearchEvent:
+			lc.handleMessageSearch(event)
+		case inconst.LiveChatListMentionEvent:
+			lc.sendMentionList(event)
+		case inconst.LiveChatInviteEvent:
+			lc.handleRoomInvite(event)
+		case inconst.LiveChatDeclineInviteEvent:
+			lc.handleDeclineInvite(event)
+		case inconst.LiveChatListInviteEvent:
+			lc.sendInviteList()
+		case inconst.LiveChatInviteCodeEvent:
+			lc.handleInviteCode(event)
+		case inconst.LiveChatUpdateRoomEvent:
+			lc.handleUpdateRoom(event)
+		case inconst.LiveChatSetVisibilityEvent:
+			lc.handleSetVisibility(event)
+		case inconst.LiveChatKickEvent:
+			lc.handleModeration(event, inconst.ModerationKick)
+		case inconst.LiveChatBanEvent:
+			lc.handleModeration(event, inconst.ModerationBan)
+		case inconst.LiveChatUnbanEvent:
+			lc.handleModeration(event, inconst.ModerationUnban)
+		case inconst.LiveChatMuteEvent:
+			lc.handleModeration(event, inconst.ModerationMute)
+		case inconst.LiveChatUnmuteEvent:
+			lc.handleModeration(event, inconst.ModerationUnmute)
+		case inconst.LiveChatSetRoleEvent:
+			lc.handleModeration(event, inconst.ModerationSetRole)
+		case inconst.LiveChatThreadReplyEvent:
+			lc.handleThreadReply(event)
+		case inconst.LiveChatThreadLogEvent:
+			lc.sendThreadLog(parseChatLogPayload(event.Data))
+		case inconst.LiveChatEditMsgEvent:
+			lc.handleMessageEdit(event)
+		case inconst.LiveChatDeleteMsgEvent:
+			lc.handleMessageDelete(event)
+		case inconst.LiveChatReactAddEvent:
+			lc.handleReaction(event, true)
+		case inconst.LiveChatReactRemoveEvent:
+			lc.handleReaction(event, false)
+		case inconst.LiveChatTypingStartEvent:
+			lc.handleTyping(event, true)
+		case inconst.LiveChatTypingStopEvent:
+			lc.handleTyping(event, false)
+		case inconst.LiveChatPresenceSetEvent:
+			lc.handlePresenceSet(event)
+		case inconst.LiveChatPresenceQueryEvent:
+			lc.handlePresenceQuery(event)
+		case inconst.LiveChatRoomLogEvent:
+			payload := parseChatLogPayload(event.Data)
+
+			roomMeta := lc.findJoinedRoom(&indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName})
+			if roomMeta == nil {
+				continue
+			}
+
+			lc.sendChatLog(&indto.ChatHistoryParams{RoomID: roomMeta.ID}, payload)
+		case inconst.LiveChatDirectLogEvent:
+			payload := parseChatLogPayload(event.Data)
+
+			peerMeta, err := lc.repo.FindUser(lc.ctx, &indto.UserParams{Username: payload.Username})
+			if err != nil {
+				lc.logger.Error().Err(err).Msg("failed to fetch recipient meta")
+				lc.in <- dto.LiveChatSocketEvent{
+					EventName: inconst.LiveChatErrorMsgEvent,
+					Data:      "failed to fetch recipient meta",
+				}
+				continue
+			} else if peerMeta == nil {
+				lc.logger.Error().Err(err).Msg("recipient doesnt existed")
 				lc.in <- dto.LiveChatSocketEvent{
 					EventName: inconst.LiveChatErrorMsgEvent,
-					Data:      "failed to save message",
+					Data:      "recipient doesnt exists",
 				}
 				continue
 			}
 
-			lc.hub.msgChan <- &dto.LiveChatSocketRequest{
-				SenderID:    lc.UserID,
-				RecipientID: recipientMeta.ID,
-				Event: dto.LiveChatSocketEvent{
-					EventName: inconst.LiveChatIncomingMsgEvent,
-					Data:      incomingMessage,
-				},
-			}
-			// case inconst.LiveChatRoomLogEvent:
-			// 	msg, err := lc.repo.FindChatHistory(lc.ctx, &indto.ChatHistoryParams{RoomID: lc.activeRoomID})
-			// 	if err != nil {
-			// 		lc.logger.Error().Err(err).Msg("failed to get message log")
-			// 		lc.in <- dto.LiveChatSocketEvent{
-			// 			EventName: inconst.LiveChatErrorMsgEvent,
-			// 			Data:      "failed to get message log",
-			// 		}
-			// 		continue
-			// 	}
-
-			// 	pastMessage := []*indto.IncomingMessage{}
-			// 	for _, m := range msg {
-			// 		pastMessage = append(pastMessage, &indto.IncomingMessage{
-			// 			SenderID:   m.SenderID,
-			// 			SenderName: m.SenderName,
-			// 			Content:    m.Message,
-			// 		})
-			// 	}
-
-			// 	lc.in <- dto.LiveChatSocketEvent{
-			// 		EventName: inconst.LiveChatMsgLogEvent,
-			// 		Data:      pastMessage,
-			// 	}
-			// case inconst.LiveChatDirectLogEvent:
-			// 	msg, err := lc.repo.FindChatHistory(lc.ctx, &indto.ChatHistoryParams{UserID: })
-			// 	if err != nil {
-			// 		lc.logger.Error().Err(err).Msg("failed to get message log")
-			// 		lc.in <- dto.LiveChatSocketEvent{
-			// 			EventName: inconst.LiveChatErrorMsgEvent,
-			// 			Data:      "failed to get message log",
-			// 		}
-			// 		continue
-			// 	}
-
-			// 	pastMessage := []*indto.IncomingMessage{}
-			// 	for _, m := range msg {
-			// 		pastMessage = append(pastMessage, &indto.IncomingMessage{
-			// 			SenderID:   m.SenderID,
-			// 			SenderName: m.SenderName,
-			// 			Content:    m.Message,
-			// 		})
-			// 	}
-
-			// 	lc.in <- dto.LiveChatSocketEvent{
-			// 		EventName: inconst.LiveChatMsgLogEvent,
-			// 		Data:      pastMessage,
-			// 	}
+			lc.sendChatLog(&indto.ChatHistoryParams{UserID: lc.UserID, PeerID: peerMeta.ID, IsDM: true}, payload)
 		}
 	}
 }
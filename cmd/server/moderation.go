@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	defaultMuteDuration = 10 * time.Minute
+	maxMuteDuration     = 30 * 24 * time.Hour
+)
+
+// roomRoleRank order room roles by privilege, member could only moderate member of lower rank
+var roomRoleRank = map[string]int{
+	inconst.RoomRoleOwner:     3,
+	inconst.RoomRoleModerator: 2,
+	inconst.RoomRoleMember:    1,
+}
+
+func isRoomModerator(participant *model.RoomParticipant) bool {
+	return participant != nil && roomRoleRank[participant.Role] >= roomRoleRank[inconst.RoomRoleModerator]
+}
+
+// ensureCanPost reject message from non member or currently muted member of the room
+func ensureCanPost(ctx context.Context, repo inrepo.Repository, roomID int64, userID int64) (err error) {
+	participant, err := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: roomID, UserID: userID})
+	if err != nil {
+		return
+	} else if participant == nil {
+		return errs.ErrForbidden
+	}
+
+	if participant.MutedUntil != nil && participant.MutedUntil.After(time.Now()) {
+		return errs.ErrMuted
+	}
+
+	return
+}
+
+type moderationTarget struct {
+	Room        *model.ChatRoom
+	Actor       *model.RoomParticipant
+	User        *model.User
+	Participant *model.RoomParticipant // nil when the target is not a member
+}
+
+// resolveModeration ensure the actor is a moderator of the room who outrank the target user
+func resolveModeration(ctx context.Context, repo inrepo.Repository, payload *dto.RoomModerationPayload, actorID int64) (res *moderationTarget, err error) {
+	res = &moderationTarget{}
+
+	res.Room, err = findMemberRoom(ctx, repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Actor, err = repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: res.Room.ID, UserID: actorID})
+	if err != nil {
+		return nil, err
+	} else if !isRoomModerator(res.Actor) {
+		return nil, errs.ErrForbidden
+	}
+
+	if payload.Username == "" {
+		return nil, errs.ErrBadRequest
+	}
+
+	res.User, err = repo.FindUser(ctx, &indto.UserParams{Username: payload.Username})
+	if err != nil {
+		return nil, err
+	} else if res.User == nil {
+		return nil, errs.ErrNotFound
+	} else if res.User.ID == actorID {
+		return nil, errs.ErrBadRequest
+	}
+
+	res.Participant, err = repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: res.Room.ID, UserID: res.User.ID})
+	if err != nil {
+		return nil, err
+	} else if res.Participant != nil && roomRoleRank[res.Participant.Role] >= roomRoleRank[res.Actor.Role] {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
+
+// applyModeration persist moderation action, removed report whether the target should be evicted from the room
+func applyModeration(ctx context.Context, repo inrepo.Repository, target *moderationTarget, action string, payload *dto.RoomModerationPayload) (removed bool, err error) {
+	memberParams := &indto.RoomParticipantParams{RoomID: target.Room.ID, UserID: target.User.ID}
+
+	// every action other than ban and unban operate on current member
+	if target.Participant == nil && action != inconst.ModerationBan && action != inconst.ModerationUnban {
+		return false, errs.ErrNotFound
+	}
+
+	switch action {
+	case inconst.ModerationKick:
+		return true, repo.DeleteRoomParticipant(ctx, memberParams)
+	case inconst.ModerationBan:
+		err = repo.InsertRoomBan(ctx, &model.RoomBan{
+			RoomID:    target.Room.ID,
+			UserID:    target.User.ID,
+			BannedBy:  target.Actor.UserID,
+			CreatedAt: time.Now(),
+		})
+		if err != nil || target.Participant == nil {
+			return
+		}
+
+		return true, repo.DeleteRoomParticipant(ctx, memberParams)
+	case inconst.ModerationUnban:
+		return false, repo.DeleteRoomBan(ctx, memberParams)
+	case inconst.ModerationMute:
+		// compared in seconds before converting so large value never overflow into negative duration
+		duration := defaultMuteDuration
+		if payload.Duration < 0 || payload.Duration > int64(maxMuteDuration/time.Second) {
+			return false, errs.ErrBadRequest
+		} else if payload.Duration > 0 {
+			duration = time.Duration(payload.Duration) * time.Second
+		}
+
+		until := time.Now().Add(duration)
+		target.Participant.MutedUntil = &until
+	case inconst.ModerationUnmute:
+		target.Participant.MutedUntil = nil
+	case inconst.ModerationSetRole:
+		if target.Actor.Role != inconst.RoomRoleOwner {
+			return false, errs.ErrForbidden
+		} else if payload.Role != inconst.RoomRoleModerator && payload.Role != inconst.RoomRoleMember {
+			return false, errs.ErrBadRequest
+		}
+
+		target.Participant.Role = payload.Role
+	default:
+		return false, errs.ErrBadRequest
+	}
+
+	return false, repo.UpdateRoomParticipant(ctx, target.Participant)
+}
+
+func (lc *LiveChatSocketMiddleware) handleModeration(event *dto.LiveChatSocketEvent, action string) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid moderation payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.RoomModerationPayload](data)
+
+	target, err := resolveModeration(lc.ctx, lc.repo, payload, lc.UserID)
+	if err != nil {
+		lc.sendModerationError(err)
+		return
+	}
+
+	removed, err := applyModeration(lc.ctx, lc.repo, target, action, payload)
+	if err != nil {
+		lc.sendModerationError(err)
+		return
+	}
+
+	info := &indto.ModerationInfo{
+		RoomID:        target.Room.ID,
+		RoomName:      target.Room.RoomName,
+		Action:        action,
+		UserID:        target.User.ID,
+		Username:      target.User.Username,
+		ModeratorID:   lc.UserID,
+		ModeratorName: lc.username,
+	}
+	if target.Participant != nil {
+		info.Role, info.MutedUntil = target.Participant.Role, target.Participant.MutedUntil
+	}
+
+	event = &dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatModeratedEvent,
+		Data:      info,
+	}
+
+	// evicted user no longer receive room broadcast, notify their devices directly
+	if removed {
+		lc.hub.LeaveUserRoom(target.Room.ID, target.User.ID)
+		lc.hub.SendToUser(target.User.ID, *event)
+	}
+
+	lc.hub.broadcast <- dto.LiveChatBroadcastEvent{
+		Room:  target.Room.ID,
+		Event: *event,
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) sendModerationError(err error) {
+	msg := "failed to moderate user"
+	switch {
+	case errors.Is(err, errs.ErrBadRequest):
+		msg = "invalid moderation request"
+	case errors.Is(err, errs.ErrNotFound):
+		msg = "room or user doesnt exists"
+	case errors.Is(err, errs.ErrForbidden):
+		msg = "not allowed to moderate the user"
+	default:
+		lc.logger.Error().Err(err).Msg("failed to moderate user")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      msg,
+	}
+}
+
+// findManagedRoom resolve room whose the user hold at least the given role
+func findManagedRoom(ctx context.Context, repo inrepo.Repository, params *indto.ChatRoomParams, userID int64, role string) (roomMeta *model.ChatRoom, err error) {
+	roomMeta, err = findMemberRoom(ctx, repo, params, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	participant, err := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: roomMeta.ID, UserID: userID})
+	if err != nil {
+		return nil, err
+	} else if participant == nil || roomRoleRank[participant.Role] < roomRoleRank[role] {
+		return nil, errs.ErrForbidden
+	}
+
+	return
+}
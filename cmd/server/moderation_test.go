@@ -0,0 +1,212 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestResolveModeration(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	owner := createTestUser(t, repo, "owner")
+	mod := createTestUser(t, repo, "mod")
+	otherMod := createTestUser(t, repo, "othermod")
+	member := createTestUser(t, repo, "member")
+	outsider := createTestUser(t, repo, "outsider")
+
+	room := createTestRoom(t, repo, "general", map[int64]string{
+		owner.ID:    inconst.RoomRoleOwner,
+		mod.ID:      inconst.RoomRoleModerator,
+		otherMod.ID: inconst.RoomRoleModerator,
+		member.ID:   inconst.RoomRoleMember,
+	})
+
+	tests := []struct {
+		name     string
+		actorID  int64
+		roomID   int64
+		username string
+		err      error
+	}{
+		{name: "owner over moderator", actorID: owner.ID, username: "mod"},
+		{name: "owner over member", actorID: owner.ID, username: "member"},
+		{name: "moderator over member", actorID: mod.ID, username: "member"},
+		{name: "moderator over non member", actorID: mod.ID, username: "outsider"},
+		{name: "moderator over moderator", actorID: mod.ID, username: "othermod", err: errs.ErrForbidden},
+		{name: "moderator over owner", actorID: mod.ID, username: "owner", err: errs.ErrForbidden},
+		{name: "member over member", actorID: member.ID, username: "mod", err: errs.ErrForbidden},
+		{name: "non member actor", actorID: outsider.ID, username: "member", err: errs.ErrForbidden},
+		{name: "self", actorID: owner.ID, username: "owner", err: errs.ErrBadRequest},
+		{name: "missing target", actorID: owner.ID, err: errs.ErrBadRequest},
+		{name: "unknown target", actorID: owner.ID, username: "nobody", err: errs.ErrNotFound},
+		{name: "unknown room", actorID: owner.ID, roomID: room.ID + 1, username: "member", err: errs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomID := tt.roomID
+			if roomID == 0 {
+				roomID = room.ID
+			}
+
+			target, err := resolveModeration(ctx, repo, &dto.RoomModerationPayload{RoomID: roomID, Username: tt.username}, tt.actorID)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if err == nil && (target.User.Username != tt.username || target.Actor.UserID != tt.actorID) {
+				t.Fatalf("unexpected target %+v", target)
+			}
+		})
+	}
+}
+
+func TestApplyModeration(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	owner := createTestUser(t, repo, "owner")
+	mod := createTestUser(t, repo, "mod")
+	member := createTestUser(t, repo, "member")
+	createTestUser(t, repo, "outsider")
+
+	room := createTestRoom(t, repo, "general", map[int64]string{
+		owner.ID:  inconst.RoomRoleOwner,
+		mod.ID:    inconst.RoomRoleModerator,
+		member.ID: inconst.RoomRoleMember,
+	})
+
+	apply := func(actorID int64, action string, payload *dto.RoomModerationPayload) (bool, error) {
+		payload.RoomID = room.ID
+		target, err := resolveModeration(ctx, repo, payload, actorID)
+		if err != nil {
+			t.Fatalf("failed to resolve %s of %s: %v", action, payload.Username, err)
+		}
+
+		return applyModeration(ctx, repo, target, action, payload)
+	}
+
+	// only owner could change roles, and never hand over ownership
+	if _, err := apply(mod.ID, inconst.ModerationSetRole, &dto.RoomModerationPayload{Username: "member", Role: inconst.RoomRoleModerator}); !errors.Is(err, errs.ErrForbidden) {
+		t.Fatalf("moderator should not set role, got %v", err)
+	}
+
+	if _, err := apply(owner.ID, inconst.ModerationSetRole, &dto.RoomModerationPayload{Username: "member", Role: inconst.RoomRoleOwner}); !errors.Is(err, errs.ErrBadRequest) {
+		t.Fatalf("ownership should not be granted, got %v", err)
+	}
+
+	if _, err := apply(mod.ID, inconst.ModerationMute, &dto.RoomModerationPayload{Username: "outsider"}); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("non member should not be muted, got %v", err)
+	}
+
+	if _, err := apply(mod.ID, "promote", &dto.RoomModerationPayload{Username: "member"}); !errors.Is(err, errs.ErrBadRequest) {
+		t.Fatalf("unknown action should be rejected, got %v", err)
+	}
+
+	for _, duration := range []int64{-1, int64(maxMuteDuration/time.Second) + 1, math.MaxInt64} {
+		if _, err := apply(mod.ID, inconst.ModerationMute, &dto.RoomModerationPayload{Username: "member", Duration: duration}); !errors.Is(err, errs.ErrBadRequest) {
+			t.Fatalf("mute for %d seconds should be rejected, got %v", duration, err)
+		}
+	}
+
+	before := time.Now()
+	if _, err := apply(mod.ID, inconst.ModerationMute, &dto.RoomModerationPayload{Username: "member", Duration: int64(maxMuteDuration / time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	participant, _ := repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: room.ID, UserID: member.ID})
+	if participant.MutedUntil == nil || participant.MutedUntil.Before(before.Add(maxMuteDuration)) || participant.MutedUntil.After(time.Now().Add(maxMuteDuration)) {
+		t.Fatalf("expected mute for the max duration, got %v", participant.MutedUntil)
+	}
+
+	// mute without duration fall back to the default
+	before = time.Now()
+	if _, err := apply(mod.ID, inconst.ModerationMute, &dto.RoomModerationPayload{Username: "member"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ensureCanPost(ctx, repo, room.ID, member.ID); !errors.Is(err, errs.ErrMuted) {
+		t.Fatalf("muted member should not post, got %v", err)
+	}
+
+	participant, _ = repo.FindRoomParticipant(ctx, &indto.RoomParticipantParams{RoomID: room.ID, UserID: member.ID})
+	if participant.MutedUntil == nil || participant.MutedUntil.Before(before.Add(defaultMuteDuration)) || participant.MutedUntil.After(time.Now().Add(defaultMuteDuration)) {
+		t.Fatalf("expected default mute duration, got %v", participant.MutedUntil)
+	}
+
+	if _, err := apply(mod.ID, inconst.ModerationUnmute, &dto.RoomModerationPayload{Username: "member"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ensureCanPost(ctx, repo, room.ID, member.ID); err != nil {
+		t.Fatalf("unmuted member should post, got %v", err)
+	}
+
+	// non member could be banned ahead, but only member is evicted
+	if removed, err := apply(mod.ID, inconst.ModerationBan, &dto.RoomModerationPayload{Username: "outsider"}); err != nil || removed {
+		t.Fatalf("expected ban without eviction, got %v %v", removed, err)
+	}
+
+	if removed, err := apply(mod.ID, inconst.ModerationKick, &dto.RoomModerationPayload{Username: "member"}); err != nil || !removed {
+		t.Fatalf("expected member to be evicted, got %v %v", removed, err)
+	}
+
+	if err := ensureCanPost(ctx, repo, room.ID, member.ID); !errors.Is(err, errs.ErrForbidden) {
+		t.Fatalf("kicked member should not post, got %v", err)
+	}
+}
+
+func TestModerationOverSocket(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	bob.send(inconst.LiveChatKickEvent, map[string]any{"room_id": room.ID, "username": "alice"})
+	if msg := bob.expectError(); msg != "not allowed to moderate the user" {
+		t.Fatalf("member should not moderate, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatMuteEvent, map[string]any{"room_id": room.ID, "username": "bob", "duration": 60})
+	if info := decodeEvent[*indto.ModerationInfo](t, bob.expect(inconst.LiveChatModeratedEvent)); info.Action != inconst.ModerationMute || info.MutedUntil == nil {
+		t.Fatalf("unexpected moderation %+v", info)
+	}
+
+	bob.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "hey"})
+	if msg := bob.expectError(); msg != "muted in the room" {
+		t.Fatalf("expected muted, got %q", msg)
+	}
+
+	// banned user is evicted right away and can not come back until unbanned
+	alice.send(inconst.LiveChatBanEvent, map[string]any{"room_id": room.ID, "username": "bob"})
+	if info := decodeEvent[*indto.ModerationInfo](t, bob.expect(inconst.LiveChatModeratedEvent)); info.Action != inconst.ModerationBan {
+		t.Fatalf("unexpected moderation %+v", info)
+	}
+
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	if msg := bob.expectError(); msg != "banned from the room" {
+		t.Fatalf("expected banned, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatUnbanEvent, map[string]any{"room_id": room.ID, "username": "bob"})
+	for {
+		if info := decodeEvent[*indto.ModerationInfo](t, alice.expect(inconst.LiveChatModeratedEvent)); info.Action == inconst.ModerationUnban {
+			break
+		}
+	}
+
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "welcome back"})
+	bob.expectIncoming("welcome back")
+}
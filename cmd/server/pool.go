@@ -0,0 +1,63 @@
+package server
+
+import "sync"
+
+// connectionPool keep track of every live connection of a user, a user may be connected from multiple devices
+type connectionPool struct {
+	conns map[int64]map[*LiveChatSocketMiddleware]bool
+
+	mutex sync.RWMutex
+}
+
+func newConnectionPool() *connectionPool {
+	return &connectionPool{
+		conns: make(map[int64]map[*LiveChatSocketMiddleware]bool),
+	}
+}
+
+func (p *connectionPool) add(conn *LiveChatSocketMiddleware) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.conns[conn.UserID]; !ok {
+		p.conns[conn.UserID] = map[*LiveChatSocketMiddleware]bool{}
+	}
+
+	p.conns[conn.UserID][conn] = true
+}
+
+// remove drop the connection from pool, return false if the connection were already removed
+func (p *connectionPool) remove(conn *LiveChatSocketMiddleware) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	userConns, ok := p.conns[conn.UserID]
+	if !ok || !userConns[conn] {
+		return false
+	}
+
+	delete(userConns, conn)
+	if len(userConns) == 0 { // user has no device left connected
+		delete(p.conns, conn.UserID)
+	}
+
+	return true
+}
+
+func (p *connectionPool) getUserConns(userID int64) (res []*LiveChatSocketMiddleware) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for conn := range p.conns[userID] {
+		res = append(res, conn)
+	}
+
+	return
+}
+
+func (p *connectionPool) isOnline(userID int64) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return len(p.conns[userID]) != 0
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+)
+
+func TestConnectionPool(t *testing.T) {
+	pool := newConnectionPool()
+	laptop := &LiveChatSocketMiddleware{UserID: 1}
+	phone := &LiveChatSocketMiddleware{UserID: 1}
+
+	pool.add(laptop)
+	pool.add(phone)
+	if !pool.isOnline(1) || len(pool.getUserConns(1)) != 2 || pool.isOnline(2) {
+		t.Fatalf("unexpected pool state %v", pool.conns)
+	}
+
+	if !pool.remove(laptop) || pool.remove(laptop) {
+		t.Fatal("connection should only be removed once")
+	}
+
+	// user stay online while any device is connected
+	if !pool.isOnline(1) {
+		t.Fatal("user should still be online from the phone")
+	}
+
+	pool.remove(phone)
+	if pool.isOnline(1) || len(pool.conns) != 0 {
+		t.Fatalf("user should be offline, got %v", pool.conns)
+	}
+}
+
+func TestMultiDeviceDirectMessage(t *testing.T) {
+	srv := newTestServer(t)
+	aliceLaptop := srv.connect(t, "alice")
+	alicePhone := srv.connect(t, "alice")
+	bobLaptop := srv.connect(t, "bob")
+	bobPhone := srv.connect(t, "bob")
+
+	aliceLaptop.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "hi"})
+
+	// every device of both side see the message
+	for _, c := range []*testClient{bobLaptop, bobPhone, aliceLaptop, alicePhone} {
+		if msg := decodeEvent[*indto.IncomingMessage](t, c.expect(inconst.LiveChatIncomingMsgEvent)); msg.Content != "hi" {
+			t.Fatalf("expected hi, got %q", msg.Content)
+		}
+	}
+
+	// closing one device keep the user reachable from the other
+	bobLaptop.conn.Close()
+
+	alicePhone.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "still there?"})
+	msg := decodeEvent[*indto.IncomingMessage](t, bobPhone.expect(inconst.LiveChatIncomingMsgEvent))
+	if msg.Content != "still there?" {
+		t.Fatalf("expected still there?, got %q", msg.Content)
+	}
+
+	for {
+		status := decodeEvent[*indto.DeliveryStatus](t, alicePhone.expect(inconst.LiveChatDirectStatusEvent))
+		if status.MessageID != msg.ID {
+			continue
+		}
+
+		if status.Status != inconst.DeliveryStatusDelivered {
+			t.Fatalf("message should be delivered to the remaining device, got %s", status.Status)
+		}
+		break
+	}
+}
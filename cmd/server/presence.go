@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+// presenceTracker hold explicitly set away status, online/offline is derived from the connection pool
+type presenceTracker struct {
+	away map[int64]bool
+
+	mutex sync.RWMutex
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{
+		away: make(map[int64]bool),
+	}
+}
+
+func (p *presenceTracker) setAway(userID int64, away bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if away {
+		p.away[userID] = true
+	} else {
+		delete(p.away, userID)
+	}
+}
+
+func (p *presenceTracker) isAway(userID int64) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.away[userID]
+}
+
+// PresenceOf return current presence status of the user
+func (lc *LiveChatHub) PresenceOf(userID int64) string {
+	if !lc.connectionPool.isOnline(userID) {
+		return inconst.PresenceOffline
+	} else if lc.presence.isAway(userID) {
+		return inconst.PresenceAway
+	}
+
+	return inconst.PresenceOnline
+}
+
+// SetPresence explicitly switch user status between online and away
+func (lc *LiveChatHub) SetPresence(userID int64, username string, status string) {
+	lc.presenceChan <- &dto.LiveChatPresenceEvent{UserID: userID, Username: username, Status: status}
+}
+
+// publishPresence push presence change to every room co-member and contact of the user, contacts are resolved off the hub loop
+func (lc *LiveChatHub) publishPresence(info *indto.PresenceInfo) {
+	lc.tasks.push(func() {
+		lc.sendPresence(info)
+	})
+}
+
+func (lc *LiveChatHub) sendPresence(info *indto.PresenceInfo) {
+	ctx := lc.logger.WithContext(context.Background())
+
+	contacts, err := lc.repo.FindUserContacts(ctx, &indto.UserParams{ID: info.UserID})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch user contacts")
+		return
+	}
+
+	event := dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatPresenceEvent,
+		Data:      info,
+	}
+
+	for _, userID := range contacts {
+		lc.SendToUser(userID, event)
+	}
+}
+
+// markOffline persist last seen of the user once their last device disconnected
+func (lc *LiveChatHub) markOffline(conn *LiveChatSocketMiddleware) {
+	lc.presence.setAway(conn.UserID, false)
+
+	now := time.Now()
+	info := &indto.PresenceInfo{
+		UserID:     conn.UserID,
+		Username:   conn.username,
+		Status:     inconst.PresenceOffline,
+		LastSeenAt: &now,
+	}
+
+	lc.tasks.push(func() {
+		ctx := lc.logger.WithContext(context.Background())
+		if err := lc.repo.UpdateUserLastSeen(ctx, &model.User{ID: info.UserID, LastSeenAt: info.LastSeenAt}); err != nil {
+			lc.logger.Error().Err(err).Msg("failed to update last seen")
+		}
+
+		lc.sendPresence(info)
+	})
+}
+
+func (lc *LiveChatSocketMiddleware) handlePresenceSet(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid presence payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.PresenceSetPayload](data)
+
+	if payload.Status != inconst.PresenceOnline && payload.Status != inconst.PresenceAway {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "presence status should be either online or away",
+		}
+		return
+	}
+
+	lc.hub.SetPresence(lc.UserID, lc.username, payload.Status)
+}
+
+func (lc *LiveChatSocketMiddleware) handlePresenceQuery(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid presence payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.PresenceQueryPayload](data)
+
+	users, err := lc.repo.FindUsers(lc.ctx, &indto.UserParams{Usernames: payload.Usernames})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch userdata")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch userdata",
+		}
+		return
+	}
+
+	res := []*indto.PresenceInfo{}
+	for _, u := range users {
+		res = append(res, &indto.PresenceInfo{
+			UserID:     u.ID,
+			Username:   u.Username,
+			Status:     lc.hub.PresenceOf(u.ID),
+			LastSeenAt: u.LastSeenAt,
+		})
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatPresenceListEvent,
+		Data:      res,
+	}
+}
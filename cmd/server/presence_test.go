@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+// expectPresence wait for presence update of the given user
+func (c *testClient) expectPresence(username string) *indto.PresenceInfo {
+	c.t.Helper()
+
+	for {
+		info := decodeEvent[*indto.PresenceInfo](c.t, c.expect(inconst.LiveChatPresenceEvent))
+		if info.Username == username {
+			return info
+		}
+	}
+}
+
+func TestPresence(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	createTestUser(t, srv.repo, "carol")
+
+	bob := srv.connect(t, "bob")
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	// room co-member is told about every status change
+	bob.send(inconst.LiveChatPresenceSetEvent, map[string]any{"status": inconst.PresenceAway})
+	info := alice.expectPresence("bob")
+	for info.Status == inconst.PresenceOnline {
+		info = alice.expectPresence("bob") // login of bob is published off the hub loop, it may land after the join
+	}
+
+	if info.Status != inconst.PresenceAway {
+		t.Fatalf("expected bob away, got %s", info.Status)
+	}
+
+	bob.send(inconst.LiveChatPresenceSetEvent, map[string]any{"status": inconst.PresenceOffline})
+	if msg := bob.expectError(); msg != "presence status should be either online or away" {
+		t.Fatalf("unexpected error %q", msg)
+	}
+
+	alice.send(inconst.LiveChatPresenceQueryEvent, map[string]any{"usernames": []string{"alice", "bob", "carol"}})
+	statuses := map[string]*indto.PresenceInfo{}
+	for _, info := range decodeEvent[[]*indto.PresenceInfo](t, alice.expect(inconst.LiveChatPresenceListEvent)) {
+		statuses[info.Username] = info
+	}
+
+	if statuses["alice"].Status != inconst.PresenceOnline || statuses["bob"].Status != inconst.PresenceAway || statuses["carol"].Status != inconst.PresenceOffline {
+		t.Fatalf("unexpected statuses %+v %+v %+v", statuses["alice"], statuses["bob"], statuses["carol"])
+	}
+
+	// away is not remembered across sessions
+	bob.conn.Close()
+	if info := alice.expectPresence("bob"); info.Status != inconst.PresenceOffline || info.LastSeenAt == nil {
+		t.Fatalf("expected bob offline with last seen, got %+v", info)
+	}
+
+	srv.connect(t, "bob")
+	if info := alice.expectPresence("bob"); info.Status != inconst.PresenceOnline {
+		t.Fatalf("expected bob online, got %s", info.Status)
+	}
+
+	alice.send(inconst.LiveChatPresenceQueryEvent, map[string]any{"usernames": []string{"bob"}})
+	list := decodeEvent[[]*indto.PresenceInfo](t, alice.expect(inconst.LiveChatPresenceListEvent))
+	if len(list) != 1 || list[0].Status != inconst.PresenceOnline || list[0].LastSeenAt == nil {
+		t.Fatalf("expected bob online with previous last seen, got %+v", list)
+	}
+}
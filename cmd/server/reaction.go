@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+// maxEmojiLength in bytes, long enough for multi codepoint emoji sequence or a short shortcode
+const maxEmojiLength = 32
+
+// attachReactions fill aggregated reaction counts on each message
+func attachReactions(ctx context.Context, repo inrepo.Repository, msg []*indto.IncomingMessage) (err error) {
+	msgIDs := []int64{}
+	lookup := map[int64]*indto.IncomingMessage{}
+	for _, m := range msg {
+		msgIDs = append(msgIDs, m.ID)
+		lookup[m.ID] = m
+	}
+
+	counts, err := repo.FindReactionCounts(ctx, &indto.MessageReactionParams{MessageIDs: msgIDs})
+	if err != nil {
+		return
+	}
+
+	for _, c := range counts {
+		m, ok := lookup[c.MessageID]
+		if !ok {
+			continue
+		}
+
+		m.Reactions = append(m.Reactions, &indto.ReactionCount{Emoji: c.Emoji, Count: c.Count})
+	}
+
+	return
+}
+
+// handleReaction add or remove user reaction on a message, then broadcast updated counts to the message audience
+func (lc *LiveChatSocketMiddleware) handleReaction(event *dto.LiveChatSocketEvent, add bool) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid reaction payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.MessageReactionPayload](data)
+
+	if payload.Emoji == "" || len(payload.Emoji) > maxEmojiLength {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid reaction emoji",
+		}
+		return
+	}
+
+	msg, err := findAccessibleMessage(lc.ctx, lc.repo, payload.MessageID, lc.UserID)
+	if err == nil && msg.DeletedAt != nil {
+		err = errs.ErrNotFound
+	}
+
+	if err != nil {
+		errMsg := "failed to fetch message"
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrForbidden) {
+			errMsg = "message doesnt exists"
+		} else {
+			lc.logger.Error().Err(err).Msg("failed to fetch message")
+		}
+
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      errMsg,
+		}
+		return
+	}
+
+	if add {
+		err = lc.repo.InsertMessageReaction(lc.ctx, &model.MessageReaction{
+			MessageID: msg.ID,
+			UserID:    lc.UserID,
+			Emoji:     payload.Emoji,
+			CreatedAt: time.Now(),
+		})
+	} else {
+		err = lc.repo.DeleteMessageReaction(lc.ctx, &indto.MessageReactionParams{
+			MessageID: msg.ID,
+			UserID:    lc.UserID,
+			Emoji:     payload.Emoji,
+		})
+	}
+
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to update reaction")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to update reaction",
+		}
+		return
+	}
+
+	counts, err := lc.repo.FindReactionCounts(lc.ctx, &indto.MessageReactionParams{MessageIDs: []int64{msg.ID}})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch reaction count")
+		return
+	}
+
+	res := &indto.MessageReactions{
+		MessageID: msg.ID,
+		RoomID:    msg.RoomID,
+		Reactions: []*indto.ReactionCount{},
+	}
+	for _, c := range counts {
+		res.Reactions = append(res.Reactions, &indto.ReactionCount{Emoji: c.Emoji, Count: c.Count})
+	}
+
+	err = publishMessageEvent(lc.ctx, lc.repo, lc.hub, msg, dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatReactionsEvent,
+		Data:      res,
+	})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to publish message event")
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestReactions(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": "hello"})
+	msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent))
+
+	react := func(c *testClient, event string, emoji string) []*indto.ReactionCount {
+		c.send(event, map[string]any{"message_id": msg.ID, "emoji": emoji})
+		res := decodeEvent[*indto.MessageReactions](t, alice.expect(inconst.LiveChatReactionsEvent))
+		if res.MessageID != msg.ID || res.RoomID != room.ID {
+			t.Fatalf("unexpected reactions %+v", res)
+		}
+		return res.Reactions
+	}
+
+	react(alice, inconst.LiveChatReactAddEvent, "👍")
+	counts := react(bob, inconst.LiveChatReactAddEvent, "👍")
+	if len(counts) != 1 || counts[0].Emoji != "👍" || counts[0].Count != 2 {
+		t.Fatalf("expected two thumbs up, got %+v", counts)
+	}
+
+	// reacting twice with the same emoji count once
+	if counts = react(bob, inconst.LiveChatReactAddEvent, "👍"); counts[0].Count != 2 {
+		t.Fatalf("duplicate reaction should be ignored, got %+v", counts)
+	}
+
+	if counts = react(bob, inconst.LiveChatReactRemoveEvent, "👍"); len(counts) != 1 || counts[0].Count != 1 {
+		t.Fatalf("expected one thumbs up left, got %+v", counts)
+	}
+
+	if counts = react(alice, inconst.LiveChatReactRemoveEvent, "👍"); len(counts) != 0 {
+		t.Fatalf("expected no reaction left, got %+v", counts)
+	}
+
+	for _, emoji := range []string{"", strings.Repeat("x", maxEmojiLength+1)} {
+		bob.send(inconst.LiveChatReactAddEvent, map[string]any{"message_id": msg.ID, "emoji": emoji})
+		if errMsg := bob.expectError(); errMsg != "invalid reaction emoji" {
+			t.Fatalf("expected invalid emoji, got %q", errMsg)
+		}
+	}
+
+	carol.send(inconst.LiveChatReactAddEvent, map[string]any{"message_id": msg.ID, "emoji": "👍"})
+	if errMsg := carol.expectError(); errMsg != "message doesnt exists" {
+		t.Fatalf("non member should not react, got %q", errMsg)
+	}
+
+	// reaction counts are part of the history
+	react(bob, inconst.LiveChatReactAddEvent, "🎉")
+	bob.send(inconst.LiveChatRoomLogEvent, "general")
+	history := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatMsgLogEvent))
+	if len(history.Messages) != 1 || len(history.Messages[0].Reactions) != 1 || history.Messages[0].Reactions[0].Emoji != "🎉" {
+		t.Fatalf("expected reaction in history, got %+v", history.Messages)
+	}
+}
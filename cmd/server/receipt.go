@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+// handleReceipt acknowledge a message as delivered or read, then notify its sender
+func (lc *LiveChatSocketMiddleware) handleReceipt(event *dto.LiveChatSocketEvent, status string) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid ack payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.MessageAckPayload](data)
+
+	msg, err := findAccessibleMessage(lc.ctx, lc.repo, payload.MessageID, lc.UserID)
+	if err != nil {
+		errMsg := "failed to fetch message"
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrForbidden) {
+			errMsg = "message doesnt exists"
+		} else {
+			lc.logger.Error().Err(err).Msg("failed to fetch message")
+		}
+
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      errMsg,
+		}
+		return
+	}
+
+	// group conversation message has no single recipient, each participant has its own delivery record
+	if msg.RoomID == 0 && (msg.RecipientID == lc.UserID || msg.ConversationID != 0) {
+		err = lc.repo.MarkMessageDelivered(lc.ctx, &indto.MessageDeliveryParams{RecipientID: lc.UserID, MessageIDs: []int64{msg.ID}})
+		if err != nil {
+			lc.logger.Error().Err(err).Msg("failed to mark message as delivered")
+		}
+	}
+
+	if status == inconst.DeliveryStatusRead {
+		marker := &model.ReadMarker{
+			UserID:    lc.UserID,
+			RoomID:    msg.RoomID,
+			MessageID: msg.ID,
+			UpdatedAt: time.Now(),
+		}
+
+		// DM conversation is keyed by the other participant
+		if msg.ConversationID != 0 {
+			marker.ConversationID = msg.ConversationID
+		} else if msg.RoomID == 0 {
+			marker.PeerID = msg.SenderID
+			if msg.SenderID == lc.UserID {
+				marker.PeerID = msg.RecipientID
+			}
+		}
+
+		if err = lc.repo.UpsertReadMarker(lc.ctx, marker); err != nil {
+			lc.logger.Error().Err(err).Msg("failed to save read marker")
+			lc.in <- dto.LiveChatSocketEvent{
+				EventName: inconst.LiveChatErrorMsgEvent,
+				Data:      "failed to save read marker",
+			}
+			return
+		}
+	}
+
+	if msg.SenderID == lc.UserID {
+		return
+	}
+
+	lc.hub.SendToUser(msg.SenderID, dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatReceiptEvent,
+		Data: &indto.DeliveryStatus{
+			MessageID:      msg.ID,
+			RoomID:         msg.RoomID,
+			ConversationID: msg.ConversationID,
+			RecipientID:    lc.UserID,
+			RecipientName:  lc.username,
+			Status:         status,
+		},
+	})
+}
+
+func (lc *LiveChatSocketMiddleware) sendUnreadCounts() {
+	counts, err := lc.repo.FindUnreadCounts(lc.ctx, &indto.ReadMarkerParams{UserID: lc.UserID})
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch unread count")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to fetch unread count",
+		}
+		return
+	}
+
+	res := []*indto.UnreadCount{}
+	for _, c := range counts {
+		res = append(res, &indto.UnreadCount{
+			RoomID:         c.RoomID,
+			RoomName:       c.RoomName,
+			PeerID:         c.PeerID,
+			PeerName:       c.PeerName,
+			ConversationID: c.ConversationID,
+			Count:          c.Count,
+		})
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatUnreadEvent,
+		Data:      res,
+	}
+}
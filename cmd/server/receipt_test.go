@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestReceipts(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	ids := []int64{}
+	for _, content := range []string{"one", "two", "three"} {
+		alice.send(inconst.LiveChatSendRoomMsgEvent, map[string]any{"room_id": room.ID, "content": content})
+		ids = append(ids, decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)).ID)
+	}
+
+	// unread count follow the read marker
+	bob.send(inconst.LiveChatAckReadEvent, map[string]any{"message_id": ids[0]})
+	receipt := decodeEvent[*indto.DeliveryStatus](t, alice.expect(inconst.LiveChatReceiptEvent))
+	if receipt.MessageID != ids[0] || receipt.RecipientName != "bob" || receipt.Status != inconst.DeliveryStatusRead || receipt.RoomID != room.ID {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+
+	bob.send(inconst.LiveChatUnreadEvent, nil)
+	counts := decodeEvent[[]*indto.UnreadCount](t, bob.expect(inconst.LiveChatUnreadEvent))
+	if len(counts) != 1 || counts[0].RoomID != room.ID || counts[0].Count != 2 {
+		t.Fatalf("expected 2 unread in general, got %+v", counts)
+	}
+
+	// non member can not acknowledge the room message
+	carol.send(inconst.LiveChatAckReadEvent, map[string]any{"message_id": ids[1]})
+	if msg := carol.expectError(); msg != "message doesnt exists" {
+		t.Fatalf("expected message doesnt exists, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "psst"})
+	dm := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent))
+
+	bob.send(inconst.LiveChatAckDeliveredEvent, map[string]any{"message_id": dm.ID})
+	for {
+		receipt = decodeEvent[*indto.DeliveryStatus](t, alice.expect(inconst.LiveChatReceiptEvent))
+		if receipt.MessageID == dm.ID {
+			break
+		}
+	}
+
+	if receipt.Status != inconst.DeliveryStatusDelivered || receipt.RecipientName != "bob" {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+
+	bob.send(inconst.LiveChatUnreadEvent, nil)
+	counts = decodeEvent[[]*indto.UnreadCount](t, bob.expect(inconst.LiveChatUnreadEvent))
+	if len(counts) != 2 || counts[0].PeerName != "alice" && counts[1].PeerName != "alice" {
+		t.Fatalf("expected unread of both general and alice, got %+v", counts)
+	}
+
+	for _, c := range counts {
+		if c.PeerName == "alice" && c.Count != 1 {
+			t.Fatalf("expected 1 unread from alice, got %+v", c)
+		}
+	}
+
+	bob.send(inconst.LiveChatAckReadEvent, map[string]any{"message_id": dm.ID})
+	bob.send(inconst.LiveChatAckReadEvent, map[string]any{"message_id": ids[2]})
+	bob.send(inconst.LiveChatUnreadEvent, nil)
+	if counts = decodeEvent[[]*indto.UnreadCount](t, bob.expect(inconst.LiveChatUnreadEvent)); len(counts) != 0 {
+		t.Fatalf("everything should be read, got %+v", counts)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/component"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ctxKeyUser    = "user"
+	ctxKeySession = "session"
+)
+
+type RESTParams struct {
+	Repo   inrepo.Repository
+	Blob   component.BlobStore
+	Logger *zerolog.Logger
+	Hub    *LiveChatHub
+}
+
+func requestContext(c echo.Context, logger *zerolog.Logger) context.Context {
+	return logger.WithContext(c.Request().Context())
+}
+
+// writeError map known errors into its http status, unknown errors are masked as internal server error
+func writeError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errs.ErrInvalidCred), errors.Is(err, errs.ErrInvalidToken), errors.Is(err, errs.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, errs.ErrBadRequest), errors.Is(err, errs.ErrBrokenUserReq), errors.Is(err, errs.ErrInvalidMsg):
+		status = http.StatusBadRequest
+	case errors.Is(err, errs.ErrForbidden), errors.Is(err, errs.ErrMuted), errors.Is(err, errs.ErrBanned):
+		status = http.StatusForbidden
+	case errors.Is(err, errs.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errs.ErrUserExisted), errors.Is(err, errs.ErrRoomExisted):
+		status = http.StatusConflict
+	default:
+		err = errs.ErrUnknown
+	}
+
+	return c.JSON(status, dto.BaseResponse{Error: err.Error()})
+}
+
+// RequireSession reject request without a valid session token, authenticated user is stored in echo context
+func RequireSession(params *RESTParams) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := extractToken(c.Request())
+			if token == "" {
+				return writeError(c, errs.ErrUnauthorized)
+			}
+
+			user, session, err := authenticateToken(requestContext(c, params.Logger), params.Repo, token)
+			if err != nil {
+				params.Logger.Error().Err(err).Msg("failed to validate session token")
+				return writeError(c, err)
+			}
+
+			c.Set(ctxKeyUser, user)
+			c.Set(ctxKeySession, session)
+
+			return next(c)
+		}
+	}
+}
+
+func HandleAuthLogin(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		cred := &dto.AuthLoginPayload{}
+		if err = c.Bind(cred); err != nil || cred.Username == "" {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		user, err := verifyCredential(ctx, params.Repo, cred)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to validate credentials")
+			return writeError(c, err)
+		}
+
+		session, _, err := issueSession(ctx, params.Repo, user)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to issue session")
+			return writeError(c, err)
+		}
+
+		params.Logger.Info().Str("username", user.Username).Msg("user logged in")
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: session})
+	}
+}
+
+func HandleAuthLogout(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+		session := c.Get(ctxKeySession).(*model.UserSession)
+
+		if err = params.Repo.RevokeUserSession(ctx, &indto.UserSessionParams{ID: session.ID}); err != nil {
+			params.Logger.Error().Err(err).Msg("failed to revoke session")
+			return writeError(c, err)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/rs/zerolog"
+)
+
+// newTestAPI mount the REST endpoints sharing repository and hub of the socket server
+func newTestAPI(s *testServer) *echo.Echo {
+	logger := zerolog.Nop()
+	params := &RESTParams{Repo: s.repo, Hub: s.hub, Logger: &logger}
+
+	ec := echo.New()
+	api := ec.Group("/api/v1")
+	api.POST("/auth/login", HandleAuthLogin(params))
+	api.POST("/auth/logout", HandleAuthLogout(params), RequireSession(params))
+
+	authed := api.Group("", RequireSession(params))
+	authed.GET("/rooms", HandleListRooms(params))
+	authed.POST("/rooms", HandleCreateRoom(params))
+	authed.GET("/rooms/:room_id/messages", HandleRoomHistory(params))
+	authed.POST("/rooms/:room_id/messages", HandlePostRoomMessage(params))
+	authed.GET("/dm/:username/messages", HandleDirectHistory(params))
+	authed.GET("/users/:username", HandleFindUser(params))
+
+	return ec
+}
+
+// apiRequest send json request to the api and decode data of the response into res
+func apiRequest(t *testing.T, ec *echo.Echo, method, path, token string, body any, res any) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = strings.NewReader(string(b))
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	ec.ServeHTTP(rec, req)
+
+	if res != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), &dto.BaseResponse{Data: res}); err != nil {
+			t.Fatalf("failed to decode response of %s %s: %v", method, path, err)
+		}
+	}
+
+	return rec.Code
+}
+
+func TestRESTAPI(t *testing.T) {
+	srv := newTestServer(t)
+	ec := newTestAPI(srv)
+	createTestUser(t, srv.repo, "alice")
+	bob := srv.connect(t, "bob")
+
+	if code := apiRequest(t, ec, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"username": "alice", "password": "wrong"}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 on wrong password, got %d", code)
+	}
+
+	session := &dto.AuthSessionResponse{}
+	if code := apiRequest(t, ec, http.MethodPost, "/api/v1/auth/login", "", map[string]any{"username": "alice", "password": testPassword}, session); code != http.StatusOK {
+		t.Fatalf("expected 200 on login, got %d", code)
+	}
+
+	if code := apiRequest(t, ec, http.MethodGet, "/api/v1/rooms", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+
+	room := &indto.RoomInfo{}
+	if code := apiRequest(t, ec, http.MethodPost, "/api/v1/rooms", session.Token, map[string]any{"room_name": "general"}, room); code != http.StatusCreated || room.ID == 0 {
+		t.Fatalf("expected room created, got %d %+v", code, room)
+	}
+
+	if code := apiRequest(t, ec, http.MethodPost, "/api/v1/rooms", session.Token, map[string]any{"room_name": "general"}, nil); code != http.StatusConflict {
+		t.Fatalf("expected 409 on duplicate room, got %d", code)
+	}
+
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	// message posted over http reach live member like any other message
+	posted := &indto.IncomingMessage{}
+	path := "/api/v1/rooms/" + fmt.Sprint(room.ID) + "/messages"
+	if code := apiRequest(t, ec, http.MethodPost, path, session.Token, map[string]any{"content": "from rest"}, posted); code != http.StatusCreated {
+		t.Fatalf("expected message posted, got %d", code)
+	}
+
+	if msg := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent)); msg.ID != posted.ID {
+		t.Fatalf("expected message %d, got %d", posted.ID, msg.ID)
+	}
+
+	history := &indto.ChatLogResponse{}
+	if code := apiRequest(t, ec, http.MethodGet, path+"?limit=10", session.Token, nil, history); code != http.StatusOK || len(history.Messages) != 1 || history.Messages[0].ID != posted.ID {
+		t.Fatalf("unexpected history %d %+v", code, history)
+	}
+
+	if code := apiRequest(t, ec, http.MethodGet, "/api/v1/rooms/abc/messages", session.Token, nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 on invalid room id, got %d", code)
+	}
+
+	if code := apiRequest(t, ec, http.MethodGet, "/api/v1/users/nobody", session.Token, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 on unknown user, got %d", code)
+	}
+
+	// revoked token is rejected right away
+	if code := apiRequest(t, ec, http.MethodPost, "/api/v1/auth/logout", session.Token, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204 on logout, got %d", code)
+	}
+
+	if code := apiRequest(t, ec, http.MethodGet, "/api/v1/rooms", session.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after logout, got %d", code)
+	}
+}
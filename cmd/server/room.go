@@ -3,14 +3,14 @@ package server
 import "sync"
 
 type rooms struct {
-	rooms map[int64]map[int64]*LiveChatSocketMiddleware
+	rooms map[int64]map[*LiveChatSocketMiddleware]bool
 
 	mutex sync.RWMutex
 }
 
 func newRooms() *rooms {
 	return &rooms{
-		rooms: make(map[int64]map[int64]*LiveChatSocketMiddleware),
+		rooms: make(map[int64]map[*LiveChatSocketMiddleware]bool),
 	}
 }
 
@@ -20,21 +20,44 @@ func (r *rooms) joinRoom(roomID int64, conn *LiveChatSocketMiddleware) {
 
 	// initialize new room if not existed before
 	if _, ok := r.rooms[roomID]; !ok {
-		r.rooms[roomID] = map[int64]*LiveChatSocketMiddleware{}
-	}
-
-	if _, ok := r.rooms[roomID][conn.UserID]; !ok {
-		r.rooms[roomID][conn.UserID] = conn
+		r.rooms[roomID] = map[*LiveChatSocketMiddleware]bool{}
 	}
 
+	r.rooms[roomID][conn] = true
 }
 
 func (r *rooms) leaveRoom(roomID int64, conn *LiveChatSocketMiddleware) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	r.removeConn(roomID, conn)
+}
+
+// leaveUserRooms remove every connection of the user from the room, used when user left from one of their devices
+func (r *rooms) leaveUserRooms(roomID int64, userID int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for conn := range r.rooms[roomID] {
+		if conn.UserID == userID {
+			r.removeConn(roomID, conn)
+		}
+	}
+}
+
+// leaveAllRooms remove connection from every room it joined, used when the connection is closed
+func (r *rooms) leaveAllRooms(conn *LiveChatSocketMiddleware) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for roomID := range r.rooms {
+		r.removeConn(roomID, conn)
+	}
+}
+
+func (r *rooms) removeConn(roomID int64, conn *LiveChatSocketMiddleware) {
 	if room, ok := r.rooms[roomID]; ok {
-		delete(room, conn.UserID) // remove connection from room
+		delete(room, conn) // remove connection from room
 
 		if len(room) == 0 { // if room is empty, also remove room from pool
 			delete(r.rooms, roomID)
@@ -42,6 +65,26 @@ func (r *rooms) leaveRoom(roomID int64, conn *LiveChatSocketMiddleware) {
 	}
 }
 
-func (r *rooms) getRoom(roomID int64) map[int64]*LiveChatSocketMiddleware {
-	return r.rooms[roomID]
+func (r *rooms) getRoom(roomID int64) (res []*LiveChatSocketMiddleware) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for conn := range r.rooms[roomID] {
+		res = append(res, conn)
+	}
+
+	return
+}
+
+// onlineCount count distinct users currently connected to the room
+func (r *rooms) onlineCount(roomID int64) int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	users := map[int64]bool{}
+	for conn := range r.rooms[roomID] {
+		users[conn.UserID] = true
+	}
+
+	return len(users)
 }
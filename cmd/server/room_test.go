@@ -0,0 +1,50 @@
+package server
+
+import "testing"
+
+func TestRooms(t *testing.T) {
+	r := newRooms()
+	aliceLaptop := &LiveChatSocketMiddleware{UserID: 1}
+	alicePhone := &LiveChatSocketMiddleware{UserID: 1}
+	bob := &LiveChatSocketMiddleware{UserID: 2}
+
+	// a connection may sit in several rooms at once
+	r.joinRoom(10, aliceLaptop)
+	r.joinRoom(20, aliceLaptop)
+	r.joinRoom(10, alicePhone)
+	r.joinRoom(10, bob)
+	r.joinRoom(20, bob)
+
+	if len(r.getRoom(10)) != 3 || len(r.getRoom(20)) != 2 {
+		t.Fatalf("unexpected subscribers %d %d", len(r.getRoom(10)), len(r.getRoom(20)))
+	}
+
+	if r.onlineCount(10) != 2 {
+		t.Fatalf("devices of the same user should be counted once, got %d", r.onlineCount(10))
+	}
+
+	// leaving from one device take every device of the user out of that room only
+	r.leaveUserRooms(10, 1)
+	if subs := r.getRoom(10); len(subs) != 1 || subs[0] != bob {
+		t.Fatalf("only bob should be left in room 10, got %v", subs)
+	}
+
+	if subs := r.getRoom(20); len(subs) != 2 {
+		t.Fatalf("other room should be kept, got %v", subs)
+	}
+
+	// closed connection drop every subscription, emptied room is released
+	r.leaveAllRooms(bob)
+	if len(r.getRoom(10)) != 0 || len(r.getRoom(20)) != 1 {
+		t.Fatalf("bob should be removed from every room")
+	}
+
+	if _, ok := r.rooms[10]; ok {
+		t.Fatal("empty room should be removed")
+	}
+
+	r.leaveRoom(20, aliceLaptop)
+	if len(r.rooms) != 0 {
+		t.Fatalf("expected no room left, got %v", r.rooms)
+	}
+}
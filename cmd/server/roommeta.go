@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	maxRoomTopicLength       = 250
+	maxRoomDescriptionLength = 1000
+)
+
+// updateRoomMeta apply partial update of room metadata, renaming require ownership while moderators could change topic and description,
+// every change is announced into the room as a system message
+func updateRoomMeta(ctx context.Context, repo inrepo.Repository, hub *LiveChatHub, user *model.User, payload *dto.UpdateRoomPayload) (roomMeta *model.ChatRoom, err error) {
+	role := inconst.RoomRoleModerator
+	if payload.NewName != nil {
+		role = inconst.RoomRoleOwner
+	}
+
+	roomMeta, err = findManagedRoom(ctx, repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, user.ID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []string{}
+	if payload.NewName != nil && *payload.NewName != roomMeta.RoomName {
+		if *payload.NewName == "" {
+			return nil, errs.ErrBadRequest
+		}
+
+		changes = append(changes, fmt.Sprintf("renamed the room from %q to %q", roomMeta.RoomName, *payload.NewName))
+		roomMeta.RoomName = *payload.NewName
+	}
+
+	if payload.Topic != nil && *payload.Topic != roomMeta.Topic {
+		if len(*payload.Topic) > maxRoomTopicLength {
+			return nil, errs.ErrBadRequest
+		}
+
+		roomMeta.Topic = *payload.Topic
+		if roomMeta.Topic == "" {
+			changes = append(changes, "cleared the topic")
+		} else {
+			changes = append(changes, fmt.Sprintf("changed the topic to %q", roomMeta.Topic))
+		}
+	}
+
+	if payload.Description != nil && *payload.Description != roomMeta.Description {
+		if len(*payload.Description) > maxRoomDescriptionLength {
+			return nil, errs.ErrBadRequest
+		}
+
+		roomMeta.Description = *payload.Description
+		changes = append(changes, "updated the description")
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	notices := []string{}
+	for _, change := range changes {
+		notices = append(notices, fmt.Sprintf("%s %s", user.Username, change))
+	}
+
+	// renaming onto a taken name is rejected by the database with ErrRoomExisted, leaving nothing announced
+	_, err = postSystemMessages(ctx, repo, hub, roomMeta, notices, func(tx inrepo.Repository) error {
+		return tx.UpdateRoom(ctx, roomMeta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hub.broadcast <- dto.LiveChatBroadcastEvent{
+		Room: roomMeta.ID,
+		Event: dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatRoomUpdatedEvent,
+			Data:      toRoomInfos([]*model.ChatRoom{roomMeta})[0],
+		},
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) handleUpdateRoom(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid room payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.UpdateRoomPayload](data)
+
+	_, err := updateRoomMeta(lc.ctx, lc.repo, lc.hub, lc.user(), payload)
+	if errors.Is(err, errs.ErrRoomExisted) {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "room already exists",
+		}
+	} else if err != nil {
+		lc.sendRoomManageError(err)
+	}
+}
+
+func HandleUpdateRoom(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		roomID, err := paramID(c, "room_id")
+		if err != nil {
+			return writeError(c, err)
+		}
+
+		payload := &dto.UpdateRoomPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+		payload.RoomID, payload.RoomName = roomID, ""
+
+		roomMeta, err := updateRoomMeta(ctx, params.Repo, params.Hub, sessionUser(c), payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to update room")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: toRoomInfos([]*model.ChatRoom{roomMeta})[0]})
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestUpdateRoomMeta(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+
+	alice.createRoom(&dto.CreateRoomPayload{RoomName: "lobby"})
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general", Topic: "chit chat"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	bob.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "topic": "mine now"})
+	if msg := bob.expectError(); msg != "not allowed to manage the room" {
+		t.Fatalf("member should not update the room, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatSetRoleEvent, map[string]any{"room_id": room.ID, "username": "bob", "role": inconst.RoomRoleModerator})
+	bob.expect(inconst.LiveChatModeratedEvent)
+
+	// moderator could change topic and description but not the name
+	bob.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "new_name": "bobs"})
+	if msg := bob.expectError(); msg != "not allowed to manage the room" {
+		t.Fatalf("moderator should not rename the room, got %q", msg)
+	}
+
+	bob.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "description": "all about chatting"})
+	if notice := alice.expectIncoming("bob updated the description"); !notice.IsSystem || notice.RoomID != room.ID {
+		t.Fatalf("expected system notice, got %+v", notice)
+	}
+
+	// field left out of the payload is untouched
+	updated := decodeEvent[*indto.RoomInfo](t, alice.expect(inconst.LiveChatRoomUpdatedEvent))
+	if updated.Topic != "chit chat" || updated.Description != "all about chatting" {
+		t.Fatalf("unexpected room %+v", updated)
+	}
+
+	alice.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "topic": strings.Repeat("x", maxRoomTopicLength+1)})
+	if msg := alice.expectError(); msg != "invalid room request" {
+		t.Fatalf("expected invalid room request, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "new_name": "lobby"})
+	if msg := alice.expectError(); msg != "room already exists" {
+		t.Fatalf("expected room already exists, got %q", msg)
+	}
+
+	alice.send(inconst.LiveChatUpdateRoomEvent, map[string]any{"room_id": room.ID, "new_name": "main", "topic": ""})
+	bob.expectIncoming(`alice renamed the room from "general" to "main"`)
+	bob.expectIncoming("alice cleared the topic")
+
+	updated = decodeEvent[*indto.RoomInfo](t, bob.expect(inconst.LiveChatRoomUpdatedEvent))
+	if updated.ID != room.ID || updated.RoomName != "main" || updated.Topic != "" {
+		t.Fatalf("unexpected room %+v", updated)
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// buildSearchMatch quote every term so user input never reach fts5 query syntax, terms are matched together
+// and a trailing asterisk keep prefix search available
+func buildSearchMatch(query string) string {
+	terms := []string{}
+	for _, term := range strings.Fields(query) {
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.TrimRight(term, "*")
+		if term == "" {
+			continue
+		}
+
+		term = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// highlightSnippet escape the snippet as html then wrap matched terms in <mark>, so message content could never inject markup.
+// Content carrying the marker characters itself is returned without highlight rather than producing unbalanced tags
+func highlightSnippet(snippet string, content string) string {
+	if strings.ContainsAny(content, model.SnippetMatchStart+model.SnippetMatchEnd) {
+		return html.EscapeString(strings.NewReplacer(model.SnippetMatchStart, "", model.SnippetMatchEnd, "").Replace(snippet))
+	}
+
+	return strings.NewReplacer(model.SnippetMatchStart, "<mark>", model.SnippetMatchEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// parseSearchTime accept either RFC3339 timestamp or plain date, plain date used as upper bound cover the whole day
+func parseSearchTime(value string, upperBound bool) (res *time.Time, err error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.ParseInLocation(time.DateOnly, value, time.Local)
+		if err != nil {
+			return nil, errs.ErrBadRequest
+		}
+
+		if upperBound {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+
+	// stored timestamps are in server local time and compared as text
+	t = t.Local()
+	return &t, nil
+}
+
+// searchMessages resolve search filters on behalf of the user then fetch a single page of matches,
+// one extra row is queried to determine whether more page exists
+func searchMessages(ctx context.Context, repo inrepo.Repository, userID int64, payload *dto.MessageSearchPayload) (res *indto.MessageSearchResponse, err error) {
+	params := &indto.MessageSearchParams{
+		UserID:   userID,
+		Match:    buildSearchMatch(payload.Query),
+		BeforeID: payload.BeforeID,
+	}
+
+	if params.Match == "" {
+		return nil, errs.ErrBadRequest
+	}
+
+	if payload.Limit == 0 {
+		payload.Limit = defaultSearchLimit
+	} else if payload.Limit > maxSearchLimit {
+		payload.Limit = maxSearchLimit
+	}
+	params.Limit = payload.Limit + 1
+
+	if params.Since, err = parseSearchTime(payload.Since, false); err != nil {
+		return
+	}
+
+	if params.Until, err = parseSearchTime(payload.Until, true); err != nil {
+		return
+	}
+
+	if payload.RoomID != 0 || payload.RoomName != "" {
+		roomMeta, err := findMemberRoom(ctx, repo, &indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName}, userID)
+		if err != nil {
+			return nil, err
+		}
+		params.RoomID = roomMeta.ID
+	}
+
+	if payload.ConversationID != 0 {
+		if _, err = findConversation(ctx, repo, payload.ConversationID, userID); err != nil {
+			return
+		}
+		params.ConversationID = payload.ConversationID
+	}
+
+	if payload.Username != "" {
+		peerMeta, err := repo.FindUser(ctx, &indto.UserParams{Username: payload.Username})
+		if err != nil {
+			return nil, err
+		} else if peerMeta == nil {
+			return nil, errs.ErrNotFound
+		}
+		params.PeerID = peerMeta.ID
+	}
+
+	if payload.Sender != "" {
+		senderMeta, err := repo.FindUser(ctx, &indto.UserParams{Username: payload.Sender})
+		if err != nil {
+			return nil, err
+		} else if senderMeta == nil {
+			return nil, errs.ErrNotFound
+		}
+		params.SenderID = senderMeta.ID
+	}
+
+	matches, err := repo.SearchChatHistory(ctx, params)
+	if err != nil {
+		return
+	}
+
+	res = &indto.MessageSearchResponse{Results: []*indto.MessageSearchHit{}}
+	if uint64(len(matches)) > payload.Limit {
+		matches = matches[:payload.Limit]
+		res.HasMore = true
+	}
+
+	incoming := []*indto.IncomingMessage{}
+	for _, m := range matches {
+		hit := &indto.MessageSearchHit{
+			IncomingMessage: toIncomingMessages([]*model.ChatHistory{&m.ChatHistory})[0],
+			Snippet:         highlightSnippet(m.Snippet, m.Message),
+		}
+
+		res.Results = append(res.Results, hit)
+		incoming = append(incoming, hit.IncomingMessage)
+	}
+
+	if err = loadAttachments(ctx, repo, incoming); err != nil {
+		return nil, err
+	}
+
+	if len(matches) != 0 {
+		res.OldestID = matches[len(matches)-1].ID
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) handleMessageSearch(event *dto.LiveChatSocketEvent) {
+	payload := &dto.MessageSearchPayload{}
+	switch v := event.Data.(type) {
+	case string:
+		payload.Query = v
+	case map[string]any:
+		payload = structutil.MapToStruct[*dto.MessageSearchPayload](v)
+	}
+
+	res, err := searchMessages(lc.ctx, lc.repo, lc.UserID, payload)
+	if err != nil {
+		errMsg := "failed to search message"
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			errMsg = "invalid search query"
+		case errors.Is(err, errs.ErrNotFound), errors.Is(err, errs.ErrForbidden):
+			errMsg = "search scope doesnt exists"
+		default:
+			lc.logger.Error().Err(err).Msg("failed to search message")
+		}
+
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      errMsg,
+		}
+		return
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatSearchEvent,
+		Data:      res,
+	}
+}
+
+func HandleMessageSearch(params *RESTParams) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		ctx := requestContext(c, params.Logger)
+
+		payload := &dto.MessageSearchPayload{}
+		if err = c.Bind(payload); err != nil {
+			return writeError(c, errs.ErrBadRequest)
+		}
+
+		res, err := searchMessages(ctx, params.Repo, sessionUser(c).ID, payload)
+		if err != nil {
+			params.Logger.Error().Err(err).Msg("failed to search message")
+			return writeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, dto.BaseResponse{Data: res})
+	}
+}
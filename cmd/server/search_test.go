@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestBuildSearchMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "hello", want: `"hello"`},
+		{query: "  hello   world ", want: `"hello" "world"`},
+		{query: "deploy*", want: `"deploy"*`},
+		{query: "** *", want: ``},
+		{query: `say "hi"`, want: `"say" """hi"""`},
+		{query: "a OR b", want: `"a" "OR" "b"`},
+		{query: "col:value NEAR(x)", want: `"col:value" "NEAR(x)"`},
+		{query: "-excluded ^start", want: `"-excluded" "^start"`},
+		{query: "", want: ``},
+	}
+
+	for _, tt := range tests {
+		if got := buildSearchMatch(tt.query); got != tt.want {
+			t.Errorf("buildSearchMatch(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	mark := func(s string) string { return model.SnippetMatchStart + s + model.SnippetMatchEnd }
+
+	tests := []struct {
+		name    string
+		snippet string
+		content string
+		want    string
+	}{
+		{name: "plain", snippet: "say " + mark("hello") + " world", content: "say hello world", want: "say <mark>hello</mark> world"},
+		{name: "markup is escaped", snippet: `<img src=x onerror=alert(1)> ` + mark("hello"), content: `<img src=x onerror=alert(1)> hello`, want: `&lt;img src=x onerror=alert(1)&gt; <mark>hello</mark>`},
+		{name: "injected mark is escaped", snippet: "</mark><script>" + mark("x"), content: "</mark><script>x", want: "&lt;/mark&gt;&lt;script&gt;<mark>x</mark>"},
+		{name: "quote and ampersand", snippet: `"a" & 'b' ` + mark("c"), content: `"a" & 'b' c`, want: `&#34;a&#34; &amp; &#39;b&#39; <mark>c</mark>`},
+		{name: "content with marker character", snippet: mark("a") + "\x02b", content: "a\x02b", want: "ab"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet, tt.content); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchTime(t *testing.T) {
+	tests := []struct {
+		value      string
+		upperBound bool
+		want       string
+		wantErr    bool
+	}{
+		{value: ""},
+		{value: "2024-07-01", want: "2024-07-01T00:00:00Z"},
+		{value: "2024-07-01", upperBound: true, want: "2024-07-02T00:00:00Z"},
+		{value: "2024-07-01T10:00:00+07:00", want: "2024-07-01T03:00:00Z"},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSearchTime(tt.value, tt.upperBound)
+		if tt.wantErr {
+			if !errors.Is(err, errs.ErrBadRequest) {
+				t.Errorf("parseSearchTime(%q) expected bad request, got %v", tt.value, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseSearchTime(%q) unexpected error %v", tt.value, err)
+		} else if (got == nil) != (tt.want == "") || (got != nil && got.Format(time.RFC3339) != tt.want) {
+			t.Errorf("parseSearchTime(%q, %v) = %v, want %s", tt.value, tt.upperBound, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	carol := createTestUser(t, repo, "carol")
+
+	insert := func(sender, recipient *model.User, content string) int64 {
+		id, err := repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: sender.ID, RecipientID: recipient.ID, Message: content, CreatedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	xssID := insert(bob, alice, `<img src=x onerror=alert(1)> deploy done`)
+	for i := 0; i < 3; i++ {
+		insert(alice, bob, fmt.Sprintf("deploy attempt %d", i))
+	}
+	insert(bob, carol, "deploy secret") // alice has no access
+
+	res, err := searchMessages(ctx, repo, alice.ID, &dto.MessageSearchPayload{Query: "deploy", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Results) != 2 || !res.HasMore || res.OldestID != res.Results[1].ID {
+		t.Fatalf("expected first page of 2 with more, got %d has_more %v oldest %d", len(res.Results), res.HasMore, res.OldestID)
+	}
+
+	res, err = searchMessages(ctx, repo, alice.ID, &dto.MessageSearchPayload{Query: "deploy", Limit: 2, BeforeID: res.OldestID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Results) != 2 || res.HasMore {
+		t.Fatalf("expected last page of 2, got %d has_more %v", len(res.Results), res.HasMore)
+	}
+
+	hit := res.Results[1]
+	if hit.ID != xssID {
+		t.Fatalf("expected the oldest message last, got %d", hit.ID)
+	}
+
+	if strings.Contains(hit.Snippet, "<img") || !strings.Contains(hit.Snippet, "&lt;img") || !strings.Contains(hit.Snippet, "<mark>deploy</mark>") {
+		t.Fatalf("snippet is not escaped or highlighted: %q", hit.Snippet)
+	}
+
+	if hit.Content != `<img src=x onerror=alert(1)> deploy done` {
+		t.Fatalf("content must be returned as is, got %q", hit.Content)
+	}
+
+	if _, err = searchMessages(ctx, repo, alice.ID, &dto.MessageSearchPayload{Query: "  * "}); !errors.Is(err, errs.ErrBadRequest) {
+		t.Fatalf("expected bad request for empty query, got %v", err)
+	}
+}
@@ -32,8 +32,17 @@ func StartServer(conf *config.Config, logger zerolog.Logger) {
 		SQLiteDB: db,
 	})
 
+	blobStore, err := component.NewBlobStore(&component.NewBlobStoreParams{
+		Logger: logger,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialized blob store")
+	}
+	go newBlobCollector(logger, repo, blobStore).Run()
+
 	chatHub := NewLiveChatHub(&LiveChatHubParms{
 		Logger:   logger,
+		Repo:     repo,
 		MsgChan:  msgChan,
 		DoneChan: doneChan,
 	})
@@ -47,6 +56,34 @@ func StartServer(conf *config.Config, logger zerolog.Logger) {
 		}),
 	)
 
+	restParams := &RESTParams{
+		Blob:   blobStore,
+		Logger: &logger,
+		Hub:    chatHub,
+		Repo:   repo,
+	}
+
+	api := ec.Group("/api/v1")
+	api.POST("/auth/login", HandleAuthLogin(restParams))
+	api.POST("/auth/logout", HandleAuthLogout(restParams), RequireSession(restParams))
+
+	authed := api.Group("", RequireSession(restParams))
+	authed.GET("/rooms", HandleListRooms(restParams))
+	authed.GET("/rooms/directory", HandleRoomDirectory(restParams))
+	authed.POST("/rooms", HandleCreateRoom(restParams))
+	authed.PATCH("/rooms/:room_id", HandleUpdateRoom(restParams))
+	authed.GET("/rooms/:room_id/messages", HandleRoomHistory(restParams))
+	authed.POST("/rooms/:room_id/messages", HandlePostRoomMessage(restParams))
+	authed.GET("/dm/:username/messages", HandleDirectHistory(restParams))
+	authed.POST("/attachments", HandleUploadAttachment(restParams))
+	authed.GET("/attachments/:attachment_id", HandleDownloadAttachment(restParams))
+	authed.GET("/messages/search", HandleMessageSearch(restParams))
+	authed.GET("/mentions", HandleListMentions(restParams))
+	authed.GET("/conversations", HandleListConversations(restParams))
+	authed.GET("/conversations/:conversation_id/messages", HandleConversationHistory(restParams))
+	authed.GET("/users/:username", HandleFindUser(restParams))
+	authed.POST("/invites/:code", HandleJoinByInviteCode(restParams))
+
 	logger.Info().Msg("starting server")
 	if err := ec.Start(conf.ServiceAddress); err != nil {
 		logger.Error().Err(err).Msg("failed to start server")
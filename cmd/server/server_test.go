@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/gorilla/websocket"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+	"github.com/nmluci/realtime-chat-sys/internal/config"
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
+)
+
+const testPassword = "password123"
+
+func TestMain(m *testing.M) {
+	// match the server, stored timestamps are compared as text in local time
+	time.Local = time.UTC
+	config.Init("local")
+
+	os.Exit(m.Run())
+}
+
+// newTestDB open a migrated sqlite database which is removed once the test finished
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	db, err := sqlx.Connect("sqlite", fmt.Sprintf("%s?_time_format=sqlite&_txlock=immediate&_pragma=busy_timeout(5000)", dbPath))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dbMigrate, err := migrate.New("file://../../migrations", fmt.Sprintf("sqlite://%s", filepath.ToSlash(dbPath)))
+	if err != nil {
+		t.Fatalf("failed to init migration: %v", err)
+	}
+	defer dbMigrate.Close()
+
+	if err = dbMigrate.Up(); err != nil {
+		t.Fatalf("failed to migrate db: %v", err)
+	}
+
+	return db
+}
+
+func newTestRepo(t *testing.T) inrepo.Repository {
+	t.Helper()
+	return inrepo.NewRepository(&inrepo.NewRepositoryParams{SQLiteDB: newTestDB(t)})
+}
+
+func testContext() context.Context {
+	return zerolog.Nop().WithContext(context.Background())
+}
+
+func createTestUser(t *testing.T, repo inrepo.Repository, username string) *model.User {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Username: username, Password: string(hashed)}
+	if err = repo.InsertUser(testContext(), user); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	user, err = repo.FindUser(testContext(), &indto.UserParams{Username: username})
+	if err != nil || user == nil {
+		t.Fatalf("failed to fetch user %s: %v", username, err)
+	}
+
+	return user
+}
+
+type testServer struct {
+	url  string
+	repo inrepo.Repository
+	hub  *LiveChatHub
+}
+
+// newTestServer run the socket endpoint along with its hub on top of a fresh database
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithRepo(t, newTestRepo(t))
+}
+
+func newTestServerWithRepo(t *testing.T, repo inrepo.Repository) *testServer {
+	t.Helper()
+
+	logger := zerolog.Nop()
+
+	hub := NewLiveChatHub(&LiveChatHubParms{
+		Logger:   logger,
+		Repo:     repo,
+		MsgChan:  make(chan *dto.LiveChatSocketRequest, 20),
+		DoneChan: make(chan int),
+	})
+	go hub.Run()
+
+	ec := echo.New()
+	ec.Any("/api/v1/chat", HandleLiveChatSocket(&LiveChatSocketParams{Logger: &logger, Hub: hub, Repo: repo}))
+
+	srv := httptest.NewServer(ec)
+	t.Cleanup(srv.Close)
+
+	return &testServer{url: "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/chat", repo: repo, hub: hub}
+}
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// connect log the user in through the socket, the user is created when it doesnt exist yet
+func (s *testServer) connect(t *testing.T, username string) *testClient {
+	t.Helper()
+
+	user, err := s.repo.FindUser(testContext(), &indto.UserParams{Username: username})
+	if err != nil {
+		t.Fatal(err)
+	} else if user == nil {
+		createTestUser(t, s.repo, username)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(s.url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testClient{t: t, conn: conn}
+	c.send(inconst.LiveChatAuthLoginEvent, map[string]any{"username": username, "password": testPassword})
+	c.expect(inconst.LiveChatAuthAckEvent)
+
+	return c
+}
+
+func (c *testClient) send(event string, data any) {
+	c.t.Helper()
+
+	if err := c.conn.WriteJSON(dto.LiveChatSocketEvent{EventName: event, Data: data}); err != nil {
+		c.t.Fatalf("failed to send %s: %v", event, err)
+	}
+}
+
+// expect read until the named event arrive, every other event is skipped
+func (c *testClient) expect(event string) dto.LiveChatSocketEvent {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		res := dto.LiveChatSocketEvent{}
+		if err := c.conn.ReadJSON(&res); err != nil {
+			c.t.Fatalf("failed while waiting for %s: %v", event, err)
+		}
+
+		if res.EventName == event {
+			return res
+		}
+	}
+}
+
+// decodeEvent convert generic event data into the given type
+func decodeEvent[T any](t *testing.T, event dto.LiveChatSocketEvent) (res T) {
+	t.Helper()
+
+	b, err := json.Marshal(event.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = json.Unmarshal(b, &res); err != nil {
+		t.Fatalf("failed to decode %s: %v", event.EventName, err)
+	}
+
+	return
+}
+
+// createRoom create a room owned by the client and return its info
+func (c *testClient) createRoom(payload *dto.CreateRoomPayload) *indto.RoomInfo {
+	c.t.Helper()
+
+	c.send(inconst.LiveChatCreateRoomEvent, payload)
+	return decodeEvent[*indto.RoomInfo](c.t, c.expect(inconst.LiveChatCreatedEvent))
+}
+
+// expectError wait for the next error event and return its message
+func (c *testClient) expectError() string {
+	c.t.Helper()
+	return decodeEvent[string](c.t, c.expect(inconst.LiveChatErrorMsgEvent))
+}
+
+// createTestRoom create a public room straight in the database, members are given along with their role
+func createTestRoom(t *testing.T, repo inrepo.Repository, name string, members map[int64]string) *model.ChatRoom {
+	t.Helper()
+
+	now := time.Now()
+	room := &model.ChatRoom{RoomName: name, Visibility: inconst.RoomVisibilityPublic, CreatedAt: &now}
+
+	var err error
+	if room.ID, err = repo.CreateRoom(testContext(), room); err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	for userID, role := range members {
+		if err = repo.InsertRoomParticipant(testContext(), &model.RoomParticipant{RoomID: room.ID, UserID: userID, Role: role}); err != nil {
+			t.Fatalf("failed to add room member: %v", err)
+		}
+	}
+
+	return room
+}
+
+// expectIncoming wait for incoming message with the given content, echo of earlier messages is skipped
+func (c *testClient) expectIncoming(content string) *indto.IncomingMessage {
+	c.t.Helper()
+
+	for {
+		msg := decodeEvent[*indto.IncomingMessage](c.t, c.expect(inconst.LiveChatIncomingMsgEvent))
+		if msg.Content == content {
+			return msg
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	inrepo "github.com/nmluci/realtime-chat-sys/internal/repository"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+// findThreadParent fetch the top level message of a thread, replying to a reply continue its parent thread
+func findThreadParent(ctx context.Context, repo inrepo.Repository, messageID int64, userID int64) (msg *model.ChatHistory, err error) {
+	msg, err = findAccessibleMessage(ctx, repo, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.ParentID != 0 {
+		msg, err = findAccessibleMessage(ctx, repo, msg.ParentID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// threadOutgoingMessage address a reply to the same conversation as its parent
+func threadOutgoingMessage(ctx context.Context, repo inrepo.Repository, sender *model.User, parent *model.ChatHistory, envelope dto.MessageEnvelope) (msg *outgoingMessage, err error) {
+	msg = &outgoingMessage{
+		Sender:   sender,
+		ParentID: parent.ID,
+		Envelope: envelope,
+	}
+
+	if parent.RoomID != 0 {
+		msg.Room, err = repo.FindRoom(ctx, &indto.ChatRoomParams{ID: parent.RoomID})
+		if err != nil {
+			return nil, err
+		} else if msg.Room == nil {
+			return nil, errs.ErrNotFound
+		}
+	} else if parent.ConversationID != 0 {
+		msg.Conversation, err = findConversation(ctx, repo, parent.ConversationID, sender.ID)
+		if err != nil {
+			return nil, err
+		}
+	} else if parent.SenderID == sender.ID {
+		msg.Recipient = &model.User{ID: parent.RecipientID, Username: parent.RecipientName}
+	} else {
+		msg.Recipient = &model.User{ID: parent.SenderID, Username: parent.SenderName}
+	}
+
+	return
+}
+
+func (lc *LiveChatSocketMiddleware) sendThreadError(err error) {
+	errMsg := "failed to fetch message"
+	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrForbidden) {
+		errMsg = "message doesnt exists"
+	} else {
+		lc.logger.Error().Err(err).Msg("failed to fetch message")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatErrorMsgEvent,
+		Data:      errMsg,
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleThreadReply(event *dto.LiveChatSocketEvent) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid thread reply payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.ThreadReplyPayload](data)
+
+	parent, err := findThreadParent(lc.ctx, lc.repo, payload.MessageID, lc.UserID)
+	if err == nil && parent.DeletedAt != nil {
+		err = errs.ErrNotFound
+	}
+
+	if err != nil {
+		lc.sendThreadError(err)
+		return
+	}
+
+	msg, err := threadOutgoingMessage(lc.ctx, lc.repo, lc.user(), parent, payload.MessageEnvelope)
+	if err != nil {
+		lc.sendThreadError(err)
+		return
+	}
+	msg.AttachmentIDs = payload.AttachmentIDs
+
+	_, err = postMessage(lc.ctx, lc.repo, lc.hub, msg)
+	if err != nil {
+		lc.sendPostError(err)
+		return
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) sendThreadLog(payload *dto.ChatLogPayload) {
+	parent, err := findThreadParent(lc.ctx, lc.repo, payload.MessageID, lc.UserID)
+	if err != nil {
+		lc.sendThreadError(err)
+		return
+	}
+
+	res, err := fetchChatLog(lc.ctx, lc.repo, &indto.ChatHistoryParams{ParentID: parent.ID}, payload)
+	if err != nil {
+		lc.logger.Error().Err(err).Msg("failed to get thread log")
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "failed to get thread log",
+		}
+		return
+	}
+
+	res.Parent = toIncomingMessages([]*model.ChatHistory{parent})[0]
+	if err = attachReactions(lc.ctx, lc.repo, []*indto.IncomingMessage{res.Parent}); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch reaction count")
+	}
+
+	if err = loadAttachments(lc.ctx, lc.repo, []*indto.IncomingMessage{res.Parent}); err != nil {
+		lc.logger.Error().Err(err).Msg("failed to fetch attachments")
+	}
+
+	lc.in <- dto.LiveChatSocketEvent{
+		EventName: inconst.LiveChatThreadEvent,
+		Data:      res,
+	}
+}
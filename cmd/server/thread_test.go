@@ -0,0 +1,100 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+)
+
+func TestThreadReplies(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	carol := srv.connect(t, "carol")
+
+	alice.send(inconst.LiveChatSendDirectMsgEvent, map[string]any{"recipient_username": "bob", "content": "question"})
+	parent := decodeEvent[*indto.IncomingMessage](t, bob.expect(inconst.LiveChatIncomingMsgEvent))
+
+	// reply is addressed back to the sender of the parent
+	bob.send(inconst.LiveChatThreadReplyEvent, map[string]any{"message_id": parent.ID, "content": "answer"})
+	reply := alice.expectIncoming("answer")
+	if reply.ParentID != parent.ID || reply.RecipientID != parent.SenderID {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	// replying to a reply continue the same thread
+	alice.send(inconst.LiveChatThreadReplyEvent, map[string]any{"message_id": reply.ID, "content": "thanks"})
+	if nested := bob.expectIncoming("thanks"); nested.ParentID != parent.ID {
+		t.Fatalf("expected reply to stay under %d, got %d", parent.ID, nested.ParentID)
+	}
+
+	carol.send(inconst.LiveChatThreadReplyEvent, map[string]any{"message_id": parent.ID, "content": "eavesdrop"})
+	if msg := carol.expectError(); msg != "message doesnt exists" {
+		t.Fatalf("outsider should not reply, got %q", msg)
+	}
+
+	// replies are kept out of the conversation history, only counted on their parent
+	bob.send(inconst.LiveChatDirectLogEvent, "alice")
+	history := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatMsgLogEvent))
+	if len(history.Messages) != 1 || history.Messages[0].ID != parent.ID || history.Messages[0].ReplyCount != 2 {
+		t.Fatalf("expected only the parent with 2 replies, got %+v", history.Messages)
+	}
+
+	bob.send(inconst.LiveChatThreadLogEvent, map[string]any{"message_id": reply.ID})
+	thread := decodeEvent[*indto.ChatLogResponse](t, bob.expect(inconst.LiveChatThreadEvent))
+	if thread.Parent == nil || thread.Parent.ID != parent.ID || len(thread.Messages) != 2 || thread.Messages[0].ID != reply.ID {
+		t.Fatalf("unexpected thread %+v", thread)
+	}
+
+	carol.send(inconst.LiveChatThreadLogEvent, map[string]any{"message_id": parent.ID})
+	if msg := carol.expectError(); msg != "message doesnt exists" {
+		t.Fatalf("outsider should not read the thread, got %q", msg)
+	}
+}
+
+func TestUnreadCountsSkipReplies(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+
+	alice := createTestUser(t, repo, "alice")
+	bob := createTestUser(t, repo, "bob")
+	room := createTestRoom(t, repo, "general", map[int64]string{alice.ID: inconst.RoomRoleOwner, bob.ID: inconst.RoomRoleMember})
+
+	insert := func(msg *model.ChatHistory) int64 {
+		msg.SenderID, msg.CreatedAt = alice.ID, time.Now()
+
+		id, err := repo.InsertChatHistory(ctx, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	roomParent := insert(&model.ChatHistory{RoomID: room.ID, Message: "question"})
+	dmParent := insert(&model.ChatHistory{RecipientID: bob.ID, Message: "question"})
+
+	// bob has read everything in the history, replies posted afterwards are only visible within their thread
+	markers := []*model.ReadMarker{
+		{UserID: bob.ID, RoomID: room.ID, MessageID: roomParent},
+		{UserID: bob.ID, PeerID: alice.ID, MessageID: dmParent},
+	}
+	for _, m := range markers {
+		m.UpdatedAt = time.Now()
+		if err := repo.UpsertReadMarker(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert(&model.ChatHistory{RoomID: room.ID, ParentID: roomParent, Message: "reply"})
+	insert(&model.ChatHistory{RecipientID: bob.ID, ParentID: dmParent, Message: "reply"})
+
+	counts, err := repo.FindUnreadCounts(ctx, &indto.ReadMarkerParams{UserID: bob.ID})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(counts) != 0 {
+		t.Fatalf("thread replies should not be counted as unread, got %+v", counts)
+	}
+}
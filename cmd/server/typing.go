@@ -0,0 +1,150 @@
+package server
+
+import (
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+	"github.com/nmluci/realtime-chat-sys/pkg/structutil"
+)
+
+const (
+	typingTTL           = 6 * time.Second
+	typingSweepInterval = time.Second
+)
+
+type typingKey struct {
+	userID int64
+	roomID int64
+	peerID int64
+}
+
+type typingState struct {
+	username  string
+	expiresAt time.Time
+}
+
+// typingIndicators hold ephemeral typing state, only accessed from the hub loop hence no locking
+type typingIndicators struct {
+	states map[typingKey]*typingState
+}
+
+func newTypingIndicators() *typingIndicators {
+	return &typingIndicators{
+		states: make(map[typingKey]*typingState),
+	}
+}
+
+// update apply typing event into state, return true if the state were changed and need to be relayed
+func (t *typingIndicators) update(ev *dto.LiveChatTypingEvent) bool {
+	key := typingKey{userID: ev.UserID, roomID: ev.RoomID, peerID: ev.PeerID}
+	_, exists := t.states[key]
+
+	if !ev.IsTyping {
+		delete(t.states, key)
+		return exists
+	}
+
+	// repeated start only extend the expiry
+	t.states[key] = &typingState{username: ev.Username, expiresAt: time.Now().Add(typingTTL)}
+	return !exists
+}
+
+// expire remove stale typing state, either expired or owned by the given offline user when userID is non-zero
+func (t *typingIndicators) expire(now time.Time, userID int64) (res []*dto.LiveChatTypingEvent) {
+	for key, state := range t.states {
+		if now.Before(state.expiresAt) && key.userID != userID {
+			continue
+		}
+
+		delete(t.states, key)
+		res = append(res, &dto.LiveChatTypingEvent{
+			UserID:   key.userID,
+			Username: state.username,
+			RoomID:   key.roomID,
+			PeerID:   key.peerID,
+		})
+	}
+
+	return
+}
+
+// relayTyping push typing event to room member or DM peer, excluding the typist own devices
+func (lc *LiveChatHub) relayTyping(ev *dto.LiveChatTypingEvent) {
+	eventName := inconst.LiveChatTypingStopEvent
+	if ev.IsTyping {
+		eventName = inconst.LiveChatTypingStartEvent
+	}
+
+	event := dto.LiveChatSocketEvent{
+		EventName: eventName,
+		RoomID:    ev.RoomID,
+		Data: &indto.TypingIndicator{
+			UserID:   ev.UserID,
+			Username: ev.Username,
+			RoomID:   ev.RoomID,
+			IsDM:     ev.RoomID == 0,
+		},
+	}
+
+	if ev.RoomID == 0 {
+		lc.sendToUser(ev.PeerID, event)
+		return
+	}
+
+	for _, conn := range lc.rooms.getRoom(ev.RoomID) {
+		if conn.UserID == ev.UserID {
+			continue
+		}
+
+		lc.deliver(conn, event)
+	}
+}
+
+func (lc *LiveChatSocketMiddleware) handleTyping(event *dto.LiveChatSocketEvent, isTyping bool) {
+	data, ok := event.Data.(map[string]any)
+	if !ok {
+		lc.in <- dto.LiveChatSocketEvent{
+			EventName: inconst.LiveChatErrorMsgEvent,
+			Data:      "invalid typing payload",
+		}
+		return
+	}
+	payload := structutil.MapToStruct[*dto.TypingPayload](data)
+
+	typing := &dto.LiveChatTypingEvent{
+		UserID:   lc.UserID,
+		Username: lc.username,
+		IsTyping: isTyping,
+	}
+
+	if payload.Username != "" {
+		peerMeta, err := lc.repo.FindUser(lc.ctx, &indto.UserParams{Username: payload.Username})
+		if err != nil {
+			lc.logger.Error().Err(err).Msg("failed to fetch recipient meta")
+			lc.in <- dto.LiveChatSocketEvent{
+				EventName: inconst.LiveChatErrorMsgEvent,
+				Data:      "failed to fetch recipient meta",
+			}
+			return
+		} else if peerMeta == nil {
+			lc.in <- dto.LiveChatSocketEvent{
+				EventName: inconst.LiveChatErrorMsgEvent,
+				Data:      "recipient doesnt exists",
+			}
+			return
+		}
+
+		typing.PeerID = peerMeta.ID
+	} else {
+		roomMeta := lc.findJoinedRoom(&indto.ChatRoomParams{ID: payload.RoomID, RoomName: payload.RoomName})
+		if roomMeta == nil {
+			return
+		}
+
+		typing.RoomID = roomMeta.ID
+	}
+
+	lc.hub.typing <- typing
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/inconst"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
+func TestTypingIndicators(t *testing.T) {
+	state := newTypingIndicators()
+	inRoom := &dto.LiveChatTypingEvent{UserID: 1, Username: "alice", RoomID: 10, IsTyping: true}
+	inDM := &dto.LiveChatTypingEvent{UserID: 1, Username: "alice", PeerID: 2, IsTyping: true}
+	other := &dto.LiveChatTypingEvent{UserID: 3, Username: "carol", RoomID: 10, IsTyping: true}
+
+	if !state.update(inRoom) || !state.update(inDM) || !state.update(other) {
+		t.Fatal("first start should be relayed")
+	}
+
+	// repeated start is not relayed but extend the expiry
+	before := state.states[typingKey{userID: 1, roomID: 10}].expiresAt
+	time.Sleep(time.Millisecond)
+	if state.update(inRoom) {
+		t.Fatal("repeated start should not be relayed")
+	}
+
+	if !state.states[typingKey{userID: 1, roomID: 10}].expiresAt.After(before) {
+		t.Fatal("repeated start should extend the expiry")
+	}
+
+	stop := &dto.LiveChatTypingEvent{UserID: 1, RoomID: 10}
+	if !state.update(stop) || state.update(stop) {
+		t.Fatal("only stop of an active indicator should be relayed")
+	}
+
+	// nothing expire before the ttl
+	if res := state.expire(time.Now(), 0); len(res) != 0 {
+		t.Fatalf("expected nothing expired, got %v", res)
+	}
+
+	// offline user lose every indicator right away
+	res := state.expire(time.Now(), 1)
+	if len(res) != 1 || res[0].UserID != 1 || res[0].PeerID != 2 || res[0].IsTyping || res[0].Username != "alice" {
+		t.Fatalf("expected dm indicator of alice to be stopped, got %+v", res)
+	}
+
+	res = state.expire(time.Now().Add(typingTTL), 0)
+	if len(res) != 1 || res[0].UserID != 3 || res[0].RoomID != 10 {
+		t.Fatalf("expected indicator of carol to expire, got %+v", res)
+	}
+
+	if len(state.states) != 0 {
+		t.Fatalf("expected no indicator left, got %v", state.states)
+	}
+}
+
+func TestTypingRelay(t *testing.T) {
+	srv := newTestServer(t)
+	alice := srv.connect(t, "alice")
+	bob := srv.connect(t, "bob")
+	bobPhone := srv.connect(t, "bob")
+
+	room := alice.createRoom(&dto.CreateRoomPayload{RoomName: "general"})
+	bob.send(inconst.LiveChatJoinRoomEvent, "general")
+	bob.expect(inconst.LiveChatJoinedEvent)
+
+	alice.send(inconst.LiveChatTypingStartEvent, map[string]any{"room_id": room.ID})
+	for _, c := range []*testClient{bob, bobPhone} {
+		typing := decodeEvent[*indto.TypingIndicator](t, c.expect(inconst.LiveChatTypingStartEvent))
+		if typing.Username != "alice" || typing.RoomID != room.ID || typing.IsDM {
+			t.Fatalf("unexpected typing %+v", typing)
+		}
+	}
+
+	alice.send(inconst.LiveChatTypingStopEvent, map[string]any{"room_id": room.ID})
+	bob.expect(inconst.LiveChatTypingStopEvent)
+
+	// typing toward a peer stop on its own once the typist goes offline
+	bob.send(inconst.LiveChatTypingStartEvent, map[string]any{"username": "alice"})
+	if typing := decodeEvent[*indto.TypingIndicator](t, alice.expect(inconst.LiveChatTypingStartEvent)); typing.Username != "bob" || !typing.IsDM {
+		t.Fatalf("unexpected typing %+v", typing)
+	}
+
+	bob.conn.Close()
+	bobPhone.conn.Close()
+	if typing := decodeEvent[*indto.TypingIndicator](t, alice.expect(inconst.LiveChatTypingStopEvent)); typing.Username != "bob" {
+		t.Fatalf("unexpected typing %+v", typing)
+	}
+
+	alice.send(inconst.LiveChatTypingStartEvent, map[string]any{"username": "nobody"})
+	if msg := alice.expectError(); msg != "recipient doesnt exists" {
+		t.Fatalf("expected recipient doesnt exists, got %q", msg)
+	}
+}
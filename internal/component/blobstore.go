@@ -0,0 +1,46 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/nmluci/realtime-chat-sys/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// BlobStore keep immutable blobs addressed by key, writing an existing key replace it with the same content
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+	// Get return errs.ErrNotFound when the blob doesnt exist, the body may also implement io.ReadSeeker
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete is a no-op for missing blob
+	Delete(ctx context.Context, key string) error
+}
+
+type NewBlobStoreParams struct {
+	Logger zerolog.Logger
+}
+
+func NewBlobStore(params *NewBlobStoreParams) (store BlobStore, err error) {
+	conf := config.Get()
+
+	switch conf.BlobStoreConfig.Backend {
+	case config.BlobBackendS3:
+		store, err = newS3BlobStore(&conf.BlobStoreConfig.S3)
+	case config.BlobBackendFS:
+		store = newFSBlobStore(filepath.Join(conf.FilePath, "attachments"))
+	default:
+		err = fmt.Errorf("unknown blob backend: %s", conf.BlobStoreConfig.Backend)
+	}
+
+	if err != nil {
+		params.Logger.Error().Err(err).Msg("failed to init blob store")
+		return
+	}
+
+	params.Logger.Info().Str("backend", string(conf.BlobStoreConfig.Backend)).Msg("blob store init successfully")
+	return
+}
@@ -0,0 +1,96 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// fsBlobStore keep every blob as a single file named by its key within the directory
+type fsBlobStore struct {
+	dir string
+}
+
+func newFSBlobStore(dir string) *fsBlobStore {
+	return &fsBlobStore{dir: dir}
+}
+
+func (s *fsBlobStore) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || filepath.Base(key) != key {
+		return "", fmt.Errorf("invalid blob key: %q", key)
+	}
+
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put write into temporary file first so reader never observe partially written blob
+func (s *fsBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64) (err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fsBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errs.ErrNotFound
+	}
+
+	return file, err
+}
+
+func (s *fsBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *fsBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,124 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+// testBlobStoreRoundTrip exercise the BlobStore contract shared by every backend
+func testBlobStoreRoundTrip(t *testing.T, store BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+	key, content := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "hello blob"
+
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("blob shouldnt exist before put, exists %v err %v", exists, err)
+	}
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected not found before put, got %v", err)
+	}
+
+	if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	if exists, err := store.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("blob should exist after put, exists %v err %v", exists, err)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	b, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || string(b) != content {
+		t.Fatalf("expected %q, got %q err %v", content, b, err)
+	}
+
+	// content addressed key is written again on duplicated upload
+	if err = store.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("failed to overwrite: %v", err)
+	}
+
+	if err = store.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("blob shouldnt exist after delete, exists %v err %v", exists, err)
+	}
+
+	if err = store.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting missing blob should be no-op, got %v", err)
+	}
+}
+
+func TestFSBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store := newFSBlobStore(dir)
+
+	testBlobStoreRoundTrip(t, store)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected no leftover file, found %s", entries[0].Name())
+	}
+}
+
+func TestFSBlobStoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := newFSBlobStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "..", "../escape", "nested/key", "/abs"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("put %q should fail", key)
+		}
+
+		if _, err := store.Get(ctx, key); err == nil {
+			t.Errorf("get %q should fail", key)
+		}
+
+		if _, err := store.Exists(ctx, key); err == nil {
+			t.Errorf("exists %q should fail", key)
+		}
+
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("delete %q should fail", key)
+		}
+	}
+}
+
+// failingReader break in the middle of the upload
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestFSBlobStorePartialPut(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newFSBlobStore(dir)
+
+	if err := store.Put(ctx, "key", io.MultiReader(strings.NewReader("partial"), failingReader{}), 100); err == nil {
+		t.Fatal("expected put to fail")
+	}
+
+	if exists, _ := store.Exists(ctx, "key"); exists {
+		t.Fatal("partially written blob must not be visible")
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("temporary file is left behind: %s", entries[0].Name())
+	}
+}
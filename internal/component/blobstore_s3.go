@@ -0,0 +1,244 @@
+package component
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/config"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+const (
+	s3SignAlgorithm   = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // sha256 of empty body
+)
+
+// s3BlobStore talk to S3-compatible storage through its REST API signed with SigV4, objects are addressed path-style
+type s3BlobStore struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func newS3BlobStore(conf *config.S3Config) (*s3BlobStore, error) {
+	if conf.Endpoint == "" || conf.Bucket == "" || conf.AccessKey == "" || conf.SecretKey == "" {
+		return nil, errors.New("s3 endpoint, bucket and credentials are required")
+	}
+
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", conf.Endpoint)
+	}
+
+	return &s3BlobStore{
+		endpoint:  endpoint,
+		region:    conf.Region,
+		bucket:    conf.Bucket,
+		accessKey: conf.AccessKey,
+		secretKey: conf.SecretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *s3BlobStore) newRequest(ctx context.Context, method string, key string, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+func (s *s3BlobStore) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, time.Now())
+	return s.client.Do(req)
+}
+
+func (s *s3BlobStore) Put(ctx context.Context, key string, body io.Reader, size int64) (err error) {
+	req, err := s.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return
+	}
+	req.ContentLength = size
+
+	res, err := s.do(req, s3UnsignedPayload)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s3ResponseError(res)
+	}
+
+	return
+}
+
+func (s *s3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req, s3EmptyPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, errs.ErrNotFound
+	default:
+		defer res.Body.Close()
+		return nil, s3ResponseError(res)
+	}
+}
+
+func (s *s3BlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.do(req, s3EmptyPayload)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s3ResponseError(res)
+	}
+}
+
+func (s *s3BlobStore) Delete(ctx context.Context, key string) (err error) {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return
+	}
+
+	res, err := s.do(req, s3EmptyPayload)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return s3ResponseError(res)
+	}
+
+	return
+}
+
+func s3ResponseError(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("s3 %s %s responded with %d: %s", res.Request.Method, res.Request.URL.Path, res.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// sign add SigV4 authorization header, every header already set on the request is signed along with host
+func (s *s3BlobStore) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], s.region, "s3", "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := strings.Builder{}
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3SignAlgorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signingKey := s3HMAC([]byte("AWS4"+s.secretKey), amzDate[:8])
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		signingKey = s3HMAC(signingKey, part)
+	}
+	signature := hex.EncodeToString(s3HMAC(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, s.accessKey, scope, signedHeaders, signature))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath percent-encode everything except unreserved characters and the path separator
+func s3EscapePath(path string) string {
+	const unreserved = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_.~/"
+
+	res := strings.Builder{}
+	for _, b := range []byte(path) {
+		if strings.IndexByte(unreserved, b) >= 0 {
+			res.WriteByte(b)
+		} else {
+			fmt.Fprintf(&res, "%%%02X", b)
+		}
+	}
+
+	return res.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3EscapePath(key)+"="+strings.ReplaceAll(s3EscapePath(v), "/", "%2F"))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
@@ -30,7 +30,9 @@ func NewSQliteDB(params *NewSQliteDBParams) (db *sqlx.DB, err error) {
 		os.Create(connString)
 	}
 
-	db, err = sqlx.Connect("sqlite", connString)
+	// store time in sqlite native format instead of go time.String() format, transaction take the write lock upfront
+	// and concurrent writer wait for it rather than failing right away
+	db, err = sqlx.Connect("sqlite", fmt.Sprintf("%s?_time_format=sqlite&_txlock=immediate&_pragma=busy_timeout(5000)", connString))
 	if err != nil {
 		params.Logger.Error().Err(err).Msg("failed to connect to db")
 		return
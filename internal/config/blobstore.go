@@ -0,0 +1,22 @@
+package config
+
+type BlobBackend string
+
+const (
+	BlobBackendFS = BlobBackend("fs")
+	BlobBackendS3 = BlobBackend("s3")
+)
+
+type BlobStoreConfig struct {
+	Backend BlobBackend
+	S3      S3Config
+}
+
+// S3Config target any S3-compatible storage, objects are addressed path-style so self-hosted MinIO works as well
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"os"
 	"time"
 )
 
@@ -14,7 +17,9 @@ type Config struct {
 	FilePath string
 	RunSince time.Time
 
-	SqliteDBConfig SqliteDBConfig
+	SqliteDBConfig  SqliteDBConfig
+	SessionConfig   SessionConfig
+	BlobStoreConfig BlobStoreConfig
 }
 
 const logTagConfig = "[Init Config]"
@@ -26,6 +31,20 @@ func Init(envString string) {
 		SqliteDBConfig: SqliteDBConfig{
 			DBName: "app.db",
 		},
+		SessionConfig: SessionConfig{
+			Secret: os.Getenv("SESSION_SECRET"),
+			TTL:    7 * 24 * time.Hour,
+		},
+		BlobStoreConfig: BlobStoreConfig{
+			Backend: BlobBackend(os.Getenv("BLOB_BACKEND")),
+			S3: S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+			},
+		},
 	}
 
 	if envString != "dev" && envString != "prod" && envString != "local" {
@@ -44,6 +63,31 @@ func Init(envString string) {
 		conf.FilePath = "/appdata"
 	}
 
+	// issued token won't survive restart without a fixed secret
+	if conf.SessionConfig.Secret == "" {
+		if conf.Environment != EnvironmentLocal {
+			log.Printf("%s SESSION_SECRET is not set, generating ephemeral secret", logTagConfig)
+		}
+
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("%s failed to generate session secret: %v", logTagConfig, err)
+		}
+		conf.SessionConfig.Secret = hex.EncodeToString(secret)
+	}
+
+	switch conf.BlobStoreConfig.Backend {
+	case "":
+		conf.BlobStoreConfig.Backend = BlobBackendFS
+	case BlobBackendFS, BlobBackendS3:
+	default:
+		log.Fatalf("%s BLOB_BACKEND must be either fs or s3, found: %s", logTagConfig, conf.BlobStoreConfig.Backend)
+	}
+
+	if conf.BlobStoreConfig.S3.Region == "" {
+		conf.BlobStoreConfig.S3.Region = "us-east-1"
+	}
+
 	conf.RunSince = time.Now()
 	config = &conf
 }
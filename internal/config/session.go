@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+type SessionConfig struct {
+	Secret string
+	TTL    time.Duration
+}
@@ -0,0 +1,7 @@
+package inconst
+
+const (
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusQueued    = "queued"
+	DeliveryStatusRead      = "read"
+)
@@ -5,17 +5,68 @@ const (
 	LiveChatAuthSignupEvent    = LiveChatBaseEvent + "auth:signup"
 	LiveChatAuthLoginEvent     = LiveChatBaseEvent + "auth:login"
 	LiveChatAuthAckEvent       = LiveChatBaseEvent + "auth:ack"
+	LiveChatAuthTokenEvent     = LiveChatBaseEvent + "auth:token"
+	LiveChatAuthLogoutEvent    = LiveChatBaseEvent + "auth:logout"
 	LiveChatCreateRoomEvent    = LiveChatBaseEvent + "chat:create_room"
 	LiveChatCreatedEvent       = LiveChatBaseEvent + "chat:created"
 	LiveChatJoinRoomEvent      = LiveChatBaseEvent + "chat:join_room"
 	LiveChatJoinedEvent        = LiveChatBaseEvent + "chat:joined"
 	LiveChatLeaveRoomEvent     = LiveChatBaseEvent + "chat:leave_room"
 	LiveChatLeftEvent          = LiveChatBaseEvent + "chat:left"
+	LiveChatListRoomEvent      = LiveChatBaseEvent + "chat:list_room"
+	LiveChatRoomListEvent      = LiveChatBaseEvent + "chat:rooms"
+	LiveChatDirectoryEvent     = LiveChatBaseEvent + "chat:directory"
+	LiveChatSetVisibilityEvent = LiveChatBaseEvent + "chat:set_visibility"
+	LiveChatUpdateRoomEvent    = LiveChatBaseEvent + "chat:update_room"
+	LiveChatRoomUpdatedEvent   = LiveChatBaseEvent + "chat:updated"
+	LiveChatInviteEvent        = LiveChatBaseEvent + "chat:invite"
+	LiveChatInvitedEvent       = LiveChatBaseEvent + "chat:invited"
+	LiveChatAcceptInviteEvent  = LiveChatBaseEvent + "chat:accept_invite"
+	LiveChatDeclineInviteEvent = LiveChatBaseEvent + "chat:decline_invite"
+	LiveChatListInviteEvent    = LiveChatBaseEvent + "chat:list_invite"
+	LiveChatInviteListEvent    = LiveChatBaseEvent + "chat:invites"
+	LiveChatInviteCodeEvent    = LiveChatBaseEvent + "chat:invite_code"
+	LiveChatKickEvent          = LiveChatBaseEvent + "chat:kick"
+	LiveChatBanEvent           = LiveChatBaseEvent + "chat:ban"
+	LiveChatUnbanEvent         = LiveChatBaseEvent + "chat:unban"
+	LiveChatMuteEvent          = LiveChatBaseEvent + "chat:mute"
+	LiveChatUnmuteEvent        = LiveChatBaseEvent + "chat:unmute"
+	LiveChatSetRoleEvent       = LiveChatBaseEvent + "chat:set_role"
+	LiveChatModeratedEvent     = LiveChatBaseEvent + "chat:moderated"
 	LiveChatIncomingMsgEvent   = LiveChatBaseEvent + "msg:incoming"
 	LiveChatSendRoomMsgEvent   = LiveChatBaseEvent + "msg:room:send"
 	LiveChatRoomLogEvent       = LiveChatBaseEvent + "msg:room:log"
 	LiveChatSendDirectMsgEvent = LiveChatBaseEvent + "msg:dm:send"
 	LiveChatDirectLogEvent     = LiveChatBaseEvent + "msg:dm:log"
+	LiveChatDirectStatusEvent  = LiveChatBaseEvent + "msg:dm:status"
+	LiveChatSendGroupMsgEvent  = LiveChatBaseEvent + "msg:group:send"
+	LiveChatGroupLogEvent      = LiveChatBaseEvent + "msg:group:log"
+	LiveChatListGroupEvent     = LiveChatBaseEvent + "msg:group:list"
+	LiveChatGroupListEvent     = LiveChatBaseEvent + "msg:groups"
+	LiveChatAckDeliveredEvent  = LiveChatBaseEvent + "msg:ack:delivered"
+	LiveChatAckReadEvent       = LiveChatBaseEvent + "msg:ack:read"
+	LiveChatReceiptEvent       = LiveChatBaseEvent + "msg:receipt"
+	LiveChatUnreadEvent        = LiveChatBaseEvent + "msg:unread"
+	LiveChatSearchEvent        = LiveChatBaseEvent + "msg:search"
+	LiveChatEditMsgEvent       = LiveChatBaseEvent + "msg:edit"
+	LiveChatEditedMsgEvent     = LiveChatBaseEvent + "msg:edited"
+	LiveChatDeleteMsgEvent     = LiveChatBaseEvent + "msg:delete"
+	LiveChatDeletedMsgEvent    = LiveChatBaseEvent + "msg:deleted"
+	LiveChatReactAddEvent      = LiveChatBaseEvent + "msg:react:add"
+	LiveChatReactRemoveEvent   = LiveChatBaseEvent + "msg:react:remove"
+	LiveChatReactionsEvent     = LiveChatBaseEvent + "msg:reactions"
+	LiveChatThreadReplyEvent   = LiveChatBaseEvent + "msg:thread:reply"
+	LiveChatThreadLogEvent     = LiveChatBaseEvent + "msg:thread:log"
+	LiveChatThreadEvent        = LiveChatBaseEvent + "msg:thread"
+	LiveChatTypingStartEvent   = LiveChatBaseEvent + "typing:start"
+	LiveChatTypingStopEvent    = LiveChatBaseEvent + "typing:stop"
+	LiveChatPresenceSetEvent   = LiveChatBaseEvent + "presence:set"
+	LiveChatPresenceQueryEvent = LiveChatBaseEvent + "presence:query"
+	LiveChatPresenceEvent      = LiveChatBaseEvent + "presence:update"
+	LiveChatPresenceListEvent  = LiveChatBaseEvent + "presence:list"
+	LiveChatMentionEvent       = LiveChatBaseEvent + "notify:mention"
+	LiveChatListMentionEvent   = LiveChatBaseEvent + "notify:mention:list"
+	LiveChatMentionsEvent      = LiveChatBaseEvent + "notify:mentions"
 	LiveChatErrorMsgEvent      = LiveChatBaseEvent + "error"
 	LiveChatMsgLogEvent        = LiveChatBaseEvent + "msg:log"
 )
@@ -0,0 +1,10 @@
+package inconst
+
+// system message is only produced by the server, client could post every other type
+const (
+	MessageTypeText     = "text"
+	MessageTypeMarkdown = "markdown"
+	MessageTypeCode     = "code"
+	MessageTypeSystem   = "system"
+	MessageTypeCard     = "card"
+)
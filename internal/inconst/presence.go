@@ -0,0 +1,7 @@
+package inconst
+
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
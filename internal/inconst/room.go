@@ -0,0 +1,24 @@
+package inconst
+
+const (
+	RoomRoleOwner     = "owner"
+	RoomRoleModerator = "moderator"
+	RoomRoleMember    = "member"
+)
+
+// public room is listed and joinable by anyone, invite only room is listed but require an invite,
+// private room require an invite and is hidden from non member
+const (
+	RoomVisibilityPublic     = "public"
+	RoomVisibilityInviteOnly = "invite_only"
+	RoomVisibilityPrivate    = "private"
+)
+
+const (
+	ModerationKick    = "kick"
+	ModerationBan     = "ban"
+	ModerationUnban   = "unban"
+	ModerationMute    = "mute"
+	ModerationUnmute  = "unmute"
+	ModerationSetRole = "set_role"
+)
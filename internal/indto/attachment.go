@@ -0,0 +1,21 @@
+package indto
+
+import "time"
+
+type AttachmentParams struct {
+	ID            int64
+	IDs           []int64
+	MessageID     int64
+	MessageIDs    []int64
+	UploaderID    int64
+	Unlinked      bool
+	CreatedBefore time.Time
+}
+
+type AttachmentInfo struct {
+	ID          int64  `json:"id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
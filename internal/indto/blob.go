@@ -0,0 +1,9 @@
+package indto
+
+import "time"
+
+type BlobParams struct {
+	StorageKey string
+	UsedBefore time.Time
+	DeletedAt  time.Time
+}
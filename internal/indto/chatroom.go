@@ -1,10 +1,18 @@
 package indto
 
+import (
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/pkg/dto"
+)
+
 type ChatRoomParams struct {
-	ID       int64
-	RoomName string
-	IsDM     bool
-	UserID   int64
+	ID           int64
+	RoomName     string
+	InviteCode   string
+	IsDM         bool
+	UserID       int64
+	Visibilities []string
 }
 
 type RoomParticipantParams struct {
@@ -13,10 +21,76 @@ type RoomParticipantParams struct {
 	UserID int64
 }
 
+type RoomInfo struct {
+	ID          int64      `json:"id"`
+	RoomName    string     `json:"room_name"`
+	Topic       string     `json:"topic,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Visibility  string     `json:"visibility,omitempty"`
+	CreatedBy   int64      `json:"created_by,omitempty"`
+	CreatorName string     `json:"creator_name,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+type RoomDirectoryParams struct {
+	Query        string
+	Visibilities []string
+	Limit        uint64
+	Offset       uint64
+}
+
+type RoomDirectoryEntry struct {
+	*RoomInfo
+	MemberCount    int64      `json:"member_count"`
+	OnlineCount    int        `json:"online_count"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+}
+
+type RoomDirectory struct {
+	Rooms      []*RoomDirectoryEntry `json:"rooms"`
+	HasMore    bool                  `json:"has_more"`
+	NextOffset uint64                `json:"next_offset,omitempty"`
+}
+
+type RoomInviteParams struct {
+	RoomID int64
+	UserID int64
+}
+
+type RoomInviteInfo struct {
+	RoomID      int64     `json:"room_id"`
+	RoomName    string    `json:"room_name"`
+	Visibility  string    `json:"visibility"`
+	InvitedBy   int64     `json:"invited_by"`
+	InviterName string    `json:"inviter_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RoomInviteCode struct {
+	RoomID     int64  `json:"room_id"`
+	RoomName   string `json:"room_name"`
+	InviteCode string `json:"invite_code"`
+}
+
 type IncomingMessage struct {
-	SenderID    int64  `json:"sender_id"`
-	SenderName  string `json:"sender_name"`
-	RecipientID int64  `json:"recipient_id"`
-	Content     string `json:"content"`
-	IsDM        bool   `json:"is_dm"`
+	ID             int64             `json:"id"`
+	SenderID       int64             `json:"sender_id"`
+	SenderName     string            `json:"sender_name"`
+	RecipientID    int64             `json:"recipient_id"`
+	RoomID         int64             `json:"room_id"`
+	RoomName       string            `json:"room_name"`
+	Type           string            `json:"type"`
+	Content        string            `json:"content"`
+	Language       string            `json:"language,omitempty"`
+	Card           *dto.MessageCard  `json:"card,omitempty"`
+	IsDM           bool              `json:"is_dm"`
+	ConversationID int64             `json:"conversation_id,omitempty"`
+	IsSystem       bool              `json:"is_system,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	EditedAt       *time.Time        `json:"edited_at,omitempty"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+	ParentID       int64             `json:"parent_id,omitempty"`
+	ReplyCount     int64             `json:"reply_count,omitempty"`
+	Reactions      []*ReactionCount  `json:"reactions,omitempty"`
+	Attachments    []*AttachmentInfo `json:"attachments,omitempty"`
 }
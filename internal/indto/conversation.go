@@ -0,0 +1,16 @@
+package indto
+
+import "time"
+
+type ConversationParams struct {
+	ID              int64
+	ParticipantKey  string
+	UserID          int64
+	ConversationIDs []int64
+}
+
+type ConversationInfo struct {
+	ID           int64       `json:"id"`
+	Participants []*UserInfo `json:"participants"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
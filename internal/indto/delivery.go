@@ -0,0 +1,16 @@
+package indto
+
+type MessageDeliveryParams struct {
+	RecipientID int64
+	MessageIDs  []int64
+	Limit       uint64
+}
+
+type DeliveryStatus struct {
+	MessageID      int64  `json:"message_id"`
+	RoomID         int64  `json:"room_id,omitempty"`
+	ConversationID int64  `json:"conversation_id,omitempty"`
+	RecipientID    int64  `json:"recipient_id"`
+	RecipientName  string `json:"recipient_name,omitempty"`
+	Status         string `json:"status"`
+}
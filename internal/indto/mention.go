@@ -0,0 +1,20 @@
+package indto
+
+type MentionParams struct {
+	UserID       int64
+	RoomID       int64
+	Visibilities []string
+	BeforeID     int64
+	Limit        uint64
+}
+
+type MentionInfo struct {
+	Message     *IncomingMessage `json:"message"`
+	RoomMention bool             `json:"room_mention"`
+}
+
+type MentionListResponse struct {
+	Mentions []*MentionInfo `json:"mentions"`
+	HasMore  bool           `json:"has_more"`
+	OldestID int64          `json:"oldest_id"`
+}
@@ -0,0 +1,15 @@
+package indto
+
+import "time"
+
+type ModerationInfo struct {
+	RoomID        int64      `json:"room_id"`
+	RoomName      string     `json:"room_name"`
+	Action        string     `json:"action"`
+	UserID        int64      `json:"user_id"`
+	Username      string     `json:"username"`
+	ModeratorID   int64      `json:"moderator_id"`
+	ModeratorName string     `json:"moderator_name"`
+	Role          string     `json:"role,omitempty"`
+	MutedUntil    *time.Time `json:"muted_until,omitempty"`
+}
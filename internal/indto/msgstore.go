@@ -1,9 +1,23 @@
 package indto
 
 type ChatHistoryParams struct {
-	ID       int64
-	RoomID   int64
-	RoomName string
-	UserID   int64
-	IsDM     bool
+	ID             int64
+	RoomID         int64
+	RoomName       string
+	UserID         int64
+	PeerID         int64
+	ParentID       int64
+	ConversationID int64
+	IsDM           bool
+	BeforeID       int64
+	AfterID        int64
+	Limit          uint64
+}
+
+type ChatLogResponse struct {
+	Parent   *IncomingMessage   `json:"parent,omitempty"`
+	Messages []*IncomingMessage `json:"messages"`
+	HasMore  bool               `json:"has_more"`
+	OldestID int64              `json:"oldest_id"`
+	NewestID int64              `json:"newest_id"`
 }
@@ -0,0 +1,19 @@
+package indto
+
+type MessageReactionParams struct {
+	MessageID  int64
+	UserID     int64
+	Emoji      string
+	MessageIDs []int64
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+}
+
+type MessageReactions struct {
+	MessageID int64            `json:"message_id"`
+	RoomID    int64            `json:"room_id,omitempty"`
+	Reactions []*ReactionCount `json:"reactions"`
+}
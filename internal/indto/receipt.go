@@ -0,0 +1,14 @@
+package indto
+
+type ReadMarkerParams struct {
+	UserID int64
+}
+
+type UnreadCount struct {
+	RoomID         int64  `json:"room_id,omitempty"`
+	RoomName       string `json:"room_name,omitempty"`
+	PeerID         int64  `json:"peer_id,omitempty"`
+	PeerName       string `json:"peer_name,omitempty"`
+	ConversationID int64  `json:"conversation_id,omitempty"`
+	Count          int64  `json:"count"`
+}
@@ -0,0 +1,27 @@
+package indto
+
+import "time"
+
+type MessageSearchParams struct {
+	UserID         int64 // result is limited to rooms, DMs and conversations the user participate in
+	Match          string
+	RoomID         int64
+	PeerID         int64
+	ConversationID int64
+	SenderID       int64
+	Since          *time.Time
+	Until          *time.Time
+	BeforeID       int64
+	Limit          uint64
+}
+
+type MessageSearchHit struct {
+	*IncomingMessage
+	Snippet string `json:"snippet"`
+}
+
+type MessageSearchResponse struct {
+	Results  []*MessageSearchHit `json:"results"`
+	HasMore  bool                `json:"has_more"`
+	OldestID int64               `json:"oldest_id"`
+}
@@ -0,0 +1,6 @@
+package indto
+
+type UserSessionParams struct {
+	ID     string
+	UserID int64
+}
@@ -0,0 +1,8 @@
+package indto
+
+type TypingIndicator struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	RoomID   int64  `json:"room_id,omitempty"`
+	IsDM     bool   `json:"is_dm"`
+}
@@ -1,7 +1,23 @@
 package indto
 
+import "time"
+
 type UserParams struct {
-	ID       int64
-	Username string
-	Password string
+	ID        int64
+	Username  string
+	Password  string
+	Usernames []string
+	IDs       []int64
+}
+
+type UserInfo struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type PresenceInfo struct {
+	UserID     int64      `json:"user_id"`
+	Username   string     `json:"username"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
 }
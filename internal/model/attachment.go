@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Attachment is an uploaded file, it stay unlinked with message_id 0 until sent along with a message
+type Attachment struct {
+	ID          int64     `db:"id"`
+	MessageID   int64     `db:"message_id"`
+	UploaderID  int64     `db:"uploader_id"`
+	FileName    string    `db:"file_name"`
+	ContentType string    `db:"content_type"`
+	Size        int64     `db:"size"`
+	StorageKey  string    `db:"storage_key"`
+	CreatedAt   time.Time `db:"created_at"`
+}
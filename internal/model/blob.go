@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Blob track stored content by its content-addressed key, it is collected once no attachment refer to it.
+// DeletedAt is set once the collector start removing the content, such blob is never reused
+type Blob struct {
+	StorageKey string     `db:"storage_key"`
+	Size       int64      `db:"size"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt time.Time  `db:"last_used_at"`
+	DeletedAt  *time.Time `db:"deleted_at"`
+}
@@ -1,12 +1,48 @@
 package model
 
+import "time"
+
 type ChatRoom struct {
-	ID       int64  `db:"id"`
-	RoomName string `db:"room_name"`
+	ID          int64      `db:"id"`
+	RoomName    string     `db:"room_name"`
+	Topic       string     `db:"topic"`
+	Description string     `db:"description"`
+	CreatedBy   int64      `db:"created_by"`
+	CreatorName string     `db:"creator_name"`
+	Visibility  string     `db:"visibility"`
+	InviteCode  string     `db:"invite_code"`
+	CreatedAt   *time.Time `db:"created_at"` // unknown for room created before it were tracked
 }
 
 type RoomParticipant struct {
-	ID     int64 `db:"id"`
-	RoomID int64 `db:"room_id"`
-	UserID int64 `db:"user_id"`
+	ID         int64      `db:"id"`
+	RoomID     int64      `db:"room_id"`
+	UserID     int64      `db:"user_id"`
+	Role       string     `db:"role"`
+	MutedUntil *time.Time `db:"muted_until"`
+}
+
+type RoomBan struct {
+	ID        int64     `db:"id"`
+	RoomID    int64     `db:"room_id"`
+	UserID    int64     `db:"user_id"`
+	BannedBy  int64     `db:"banned_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type RoomInvite struct {
+	ID          int64     `db:"id"`
+	RoomID      int64     `db:"room_id"`
+	RoomName    string    `db:"room_name"`
+	Visibility  string    `db:"visibility"`
+	UserID      int64     `db:"user_id"`
+	InvitedBy   int64     `db:"invited_by"`
+	InviterName string    `db:"inviter_name"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type RoomDirectoryEntry struct {
+	ChatRoom
+	MemberCount    int64      `db:"member_count"`
+	LastActivityAt *time.Time `db:"last_activity_at"`
 }
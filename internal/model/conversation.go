@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Conversation is a group DM, identified by its participant set hence membership never change
+type Conversation struct {
+	ID             int64     `db:"id"`
+	ParticipantKey string    `db:"participant_key"`
+	CreatedBy      int64     `db:"created_by"`
+	CreatedAt      time.Time `db:"created_at"`
+
+	Participants []*ConversationParticipant `db:"-"`
+}
+
+type ConversationParticipant struct {
+	ID             int64  `db:"id"`
+	ConversationID int64  `db:"conversation_id"`
+	UserID         int64  `db:"user_id"`
+	Username       string `db:"username"`
+}
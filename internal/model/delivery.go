@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type MessageDelivery struct {
+	ID          int64      `db:"id"`
+	MessageID   int64      `db:"message_id"`
+	RecipientID int64      `db:"recipient_id"`
+	DeliveredAt *time.Time `db:"delivered_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
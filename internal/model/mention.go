@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type Mention struct {
+	ID            int64     `db:"id"`
+	MessageID     int64     `db:"message_id"`
+	UserID        int64     `db:"user_id"`
+	IsRoomMention bool      `db:"is_room_mention"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// MentionedMessage is a message which mention the user, either directly or through @room
+type MentionedMessage struct {
+	ChatHistory
+	IsRoomMention bool `db:"is_room_mention"`
+}
@@ -1,11 +1,22 @@
 package model
 
+import "time"
+
 type ChatHistory struct {
-	ID            int64  `db:"id"`
-	RoomID        int64  `db:"room_id"`
-	SenderID      int64  `db:"sender_id"`
-	SenderName    string `db:"sender_name"`
-	RecipientID   int64  `db:"recipient_id"`
-	RecipientName string `db:"recipient_name"`
-	Message       string `db:"message"`
+	ID             int64      `db:"id"`
+	RoomID         int64      `db:"room_id"`
+	RoomName       string     `db:"room_name"`
+	SenderID       int64      `db:"sender_id"`
+	SenderName     string     `db:"sender_name"`
+	RecipientID    int64      `db:"recipient_id"`
+	RecipientName  string     `db:"recipient_name"`
+	Message        string     `db:"message"`
+	CreatedAt      time.Time  `db:"created_at"`
+	EditedAt       *time.Time `db:"edited_at"`
+	DeletedAt      *time.Time `db:"deleted_at"`
+	ParentID       int64      `db:"parent_id"`
+	ReplyCount     int64      `db:"reply_count"`
+	ConversationID int64      `db:"conversation_id"`
+	MessageType    string     `db:"message_type"`
+	MessageMeta    string     `db:"message_meta"` // json encoded type specific content, e.g. code language or card
 }
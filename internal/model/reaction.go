@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type MessageReaction struct {
+	ID        int64     `db:"id"`
+	MessageID int64     `db:"message_id"`
+	UserID    int64     `db:"user_id"`
+	Emoji     string    `db:"emoji"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type ReactionCount struct {
+	MessageID int64  `db:"message_id"`
+	Emoji     string `db:"emoji"`
+	Count     int64  `db:"count"`
+}
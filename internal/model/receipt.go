@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ReadMarker track the last message read by user in a conversation, either a room, DM with peer or group conversation
+type ReadMarker struct {
+	ID             int64     `db:"id"`
+	UserID         int64     `db:"user_id"`
+	RoomID         int64     `db:"room_id"`
+	PeerID         int64     `db:"peer_id"`
+	ConversationID int64     `db:"conversation_id"`
+	MessageID      int64     `db:"message_id"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+type UnreadCount struct {
+	RoomID         int64  `db:"room_id"`
+	RoomName       string `db:"room_name"`
+	PeerID         int64  `db:"peer_id"`
+	PeerName       string `db:"peer_name"`
+	ConversationID int64  `db:"conversation_id"`
+	Count          int64  `db:"unread"`
+}
@@ -0,0 +1,13 @@
+package model
+
+// SnippetMatchStart and SnippetMatchEnd wrap matched terms within the search snippet, control characters are used so
+// caller could escape the content before turning them into markup
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
+type MessageSearchResult struct {
+	ChatHistory
+	Snippet string `db:"snippet"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type UserSession struct {
+	ID        string     `db:"id"`
+	UserID    int64      `db:"user_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -1,7 +1,10 @@
 package model
 
+import "time"
+
 type User struct {
-	ID       int64  `db:"id"`
-	Username string `db:"username"`
-	Password string `db:"password"`
+	ID         int64      `db:"id"`
+	Username   string     `db:"username"`
+	Password   string     `db:"password"`
+	LastSeenAt *time.Time `db:"last_seen_at"`
 }
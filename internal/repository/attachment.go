@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertAttachment(ctx context.Context, params *model.Attachment) (id int64, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("attachments").Columns("message_id", "uploader_id", "file_name", "content_type", "size", "storage_key", "created_at").
+		Values(params.MessageID, params.UploaderID, params.FileName, params.ContentType, params.Size, params.StorageKey, params.CreatedAt).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res, err := r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert attachment")
+		return
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch inserted attachment id")
+		return
+	}
+
+	return
+}
+
+func (r *repository) FindAttachment(ctx context.Context, params *indto.AttachmentParams) (res *model.Attachment, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("id", "message_id", "uploader_id", "file_name", "content_type", "size", "storage_key", "created_at").From("attachments").
+		Where(squirrel.Eq{"id": params.ID}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.Attachment{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch attachment")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+func (r *repository) FindAttachments(ctx context.Context, params *indto.AttachmentParams) (res []*model.Attachment, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{}
+	if len(params.IDs) != 0 {
+		cond = append(cond, squirrel.Eq{"id": params.IDs})
+	}
+
+	if len(params.MessageIDs) != 0 {
+		cond = append(cond, squirrel.Eq{"message_id": params.MessageIDs})
+	}
+
+	if params.UploaderID != 0 {
+		cond = append(cond, squirrel.Eq{"uploader_id": params.UploaderID})
+	}
+
+	if params.Unlinked {
+		cond = append(cond, squirrel.Eq{"message_id": 0})
+	}
+
+	res = []*model.Attachment{}
+	if len(cond) == 0 {
+		return
+	}
+
+	stmt, args, err := squirrel.Select("id", "message_id", "uploader_id", "file_name", "content_type", "size", "storage_key", "created_at").From("attachments").
+		Where(cond).OrderBy("id").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch attachments")
+		return
+	}
+
+	return
+}
+
+// LinkAttachments attach unlinked uploads of the user into the message, already linked upload is left untouched
+func (r *repository) LinkAttachments(ctx context.Context, params *indto.AttachmentParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("attachments").
+		Set("message_id", params.MessageID).
+		Where(squirrel.And{
+			squirrel.Eq{"id": params.IDs},
+			squirrel.Eq{"uploader_id": params.UploaderID},
+			squirrel.Eq{"message_id": 0},
+		}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to link attachments")
+		return
+	}
+
+	return
+}
+
+// DeleteStaleAttachments drop uploads which were never sent with a message
+func (r *repository) DeleteStaleAttachments(ctx context.Context, params *indto.AttachmentParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("attachments").Where(squirrel.And{
+		squirrel.Eq{"message_id": 0},
+		squirrel.Lt{"created_at": params.CreatedBefore},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete stale attachments")
+		return
+	}
+
+	return
+}
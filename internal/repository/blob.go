@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// UpsertBlob register blob or refresh its last use, recently used blob is spared from collection.
+// Blob whose content is being removed is left untouched and reported as not usable
+func (r *repository) UpsertBlob(ctx context.Context, params *model.Blob) (usable bool, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("blobs").Columns("storage_key", "size", "created_at", "last_used_at").
+		Values(params.StorageKey, params.Size, params.CreatedAt, params.LastUsedAt).
+		Suffix("on conflict (storage_key) do update set last_used_at = excluded.last_used_at where blobs.deleted_at is null returning storage_key").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	var key string
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).Scan(&key)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to upsert blob")
+		return
+	} else if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// unreferencedBlobCond match blobs unused since the given time which no attachment refer to
+func unreferencedBlobCond(params *indto.BlobParams) squirrel.And {
+	return squirrel.And{
+		squirrel.Lt{"last_used_at": params.UsedBefore},
+		squirrel.Expr("not exists (select 1 from attachments a where a.storage_key = blobs.storage_key)"),
+	}
+}
+
+// FindUnreferencedBlobs list collection candidates including those whose removal failed before, each must be confirmed through
+// MarkBlobDeleted
+func (r *repository) FindUnreferencedBlobs(ctx context.Context, params *indto.BlobParams) (res []*model.Blob, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("storage_key", "size", "created_at", "last_used_at", "deleted_at").From("blobs").
+		Where(unreferencedBlobCond(params)).OrderBy("last_used_at").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.Blob{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch unreferenced blobs")
+		return
+	}
+
+	return
+}
+
+// MarkBlobDeleted tombstone the blob only when it is still unreferenced and unused, nil is returned otherwise.
+// Tombstoned blob is never refreshed by UpsertBlob so its content can be removed without holding any transaction
+func (r *repository) MarkBlobDeleted(ctx context.Context, params *indto.BlobParams) (res *model.Blob, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("blobs").Set("deleted_at", squirrel.Expr("coalesce(deleted_at, ?)", params.DeletedAt)).
+		Where(append(unreferencedBlobCond(params), squirrel.Eq{"storage_key": params.StorageKey})).
+		Suffix("returning storage_key, size, created_at, last_used_at, deleted_at").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.Blob{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to mark blob deleted")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+// DeleteBlob forget tombstoned blob once its content is gone
+func (r *repository) DeleteBlob(ctx context.Context, params *indto.BlobParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("blobs").Where(squirrel.And{
+		squirrel.Eq{"storage_key": params.StorageKey},
+		squirrel.NotEq{"deleted_at": nil},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete blob")
+		return
+	}
+
+	return
+}
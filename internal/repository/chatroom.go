@@ -3,29 +3,104 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/nmluci/realtime-chat-sys/internal/indto"
 	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
 	"github.com/rs/zerolog"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// isUniqueViolation tell whether the statement failed on unique index, room name is the only one reported back to the client
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func (r *repository) roomQuery() squirrel.SelectBuilder {
+	return squirrel.Select("r.id", "r.room_name", "r.topic", "r.description", "r.created_by", "coalesce(cu.username, '') creator_name",
+		"r.visibility", "coalesce(r.invite_code, '') invite_code", "r.created_at").
+		From("rooms r").
+		LeftJoin("users cu on cu.id = r.created_by")
+}
+
 func (r *repository) FindRooms(ctx context.Context, params *indto.ChatRoomParams) (res []*model.ChatRoom, err error) {
 	logger := zerolog.Ctx(ctx)
 
-	stmt, args, err := squirrel.Select("r.id", "r.room_name", "r.is_dm", "(count(rp.id) == 1) is_joined").From("rooms r").
-		LeftJoin("room_participants rp on r.id = rp.room_id and rp.user_id = ?", params.UserID).ToSql()
+	query := r.roomQuery()
+	if params.UserID != 0 {
+		query = query.Join("room_participants rp on r.id = rp.room_id and rp.user_id = ?", params.UserID)
+	}
+
+	if len(params.Visibilities) != 0 {
+		query = query.Where(squirrel.Eq{"r.visibility": params.Visibilities})
+	}
+
+	stmt, args, err := query.OrderBy("r.room_name").ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql")
 		return
 	}
 
 	res = []*model.ChatRoom{}
-	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+
+	rows, err := r.sqliteDB.QueryxContext(ctx, stmt, args...)
 	if err != nil {
 		logger.Error().Err(err).Msg("faild to fetch room meta")
 		return
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := &model.ChatRoom{}
+
+		if err = rows.StructScan(temp); err != nil {
+			logger.Error().Err(err).Msg("failed to map row result")
+			return
+		}
+
+		res = append(res, temp)
+	}
+
+	return
+}
+
+// FindRoomDirectory list rooms ordered by member count, last activity is taken from the newest message of each room
+func (r *repository) FindRoomDirectory(ctx context.Context, params *indto.RoomDirectoryParams) (res []*model.RoomDirectoryEntry, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	query := r.roomQuery().
+		Column("(select count(rp.id) from room_participants rp where rp.room_id = r.id) member_count").
+		Column("la.created_at last_activity_at").
+		LeftJoin("chat_histories la on la.id = (select max(t.id) from chat_histories t where t.room_id = r.id)")
+
+	if len(params.Visibilities) != 0 {
+		query = query.Where(squirrel.Eq{"r.visibility": params.Visibilities})
+	}
+
+	if params.Query != "" {
+		pattern := "%" + likeEscaper.Replace(params.Query) + "%"
+		query = query.Where(`(r.room_name like ? escape '\' or r.topic like ? escape '\')`, pattern, pattern)
+	}
+
+	stmt, args, err := query.OrderBy("member_count desc", "r.id asc").
+		Limit(params.Limit).Offset(params.Offset).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.RoomDirectoryEntry{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch room directory")
+		return
+	}
 
 	return
 }
@@ -35,14 +110,16 @@ func (r *repository) FindRoom(ctx context.Context, params *indto.ChatRoomParams)
 
 	cond := squirrel.And{}
 	if params.ID != 0 {
-		cond = append(cond, squirrel.Eq{"id": params.ID})
+		cond = append(cond, squirrel.Eq{"r.id": params.ID})
 	} else if params.RoomName != "" {
-		cond = append(cond, squirrel.Eq{"room_name": params.RoomName})
+		cond = append(cond, squirrel.Eq{"r.room_name": params.RoomName})
+	} else if params.InviteCode != "" {
+		cond = append(cond, squirrel.Eq{"r.invite_code": params.InviteCode})
+	} else {
+		return nil, nil // never resolve into an arbitrary room without any filter
 	}
 
-	stmt, args, err := squirrel.Select("r.id", "r.room_name").From("rooms r").
-		LeftJoin("room_participants rp on r.id = rp.room_id and rp.user_id = ?", params.UserID).
-		Where(cond).ToSql()
+	stmt, args, err := r.roomQuery().Where(cond).ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql")
 		return
@@ -60,19 +137,227 @@ func (r *repository) FindRoom(ctx context.Context, params *indto.ChatRoomParams)
 	return
 }
 
-func (r *repository) CreateRoom(ctx context.Context, params *model.ChatRoom) (err error) {
+func (r *repository) CreateRoom(ctx context.Context, params *model.ChatRoom) (id int64, err error) {
 	logger := zerolog.Ctx(ctx)
 
-	stmt, args, err := squirrel.Insert("rooms").Columns("room_name").
-		Values(params.RoomName).ToSql()
+	stmt, args, err := squirrel.Insert("rooms").Columns("room_name", "topic", "description", "created_by", "visibility", "created_at").
+		Values(params.RoomName, params.Topic, params.Description, params.CreatedBy, params.Visibility, params.CreatedAt).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res, err := r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if isUniqueViolation(err) {
+		return 0, errs.ErrRoomExisted
+	} else if err != nil {
+		logger.Error().Err(err).Msg("faild to fetch room meta")
+		return
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch inserted room id")
+		return
+	}
+
+	return
+}
+
+// UpdateRoom update mutable attributes of the room, empty invite code revoke the current one
+func (r *repository) UpdateRoom(ctx context.Context, params *model.ChatRoom) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("rooms").
+		Set("room_name", params.RoomName).
+		Set("topic", params.Topic).
+		Set("description", params.Description).
+		Set("visibility", params.Visibility).
+		Set("invite_code", squirrel.Expr("nullif(?, '')", params.InviteCode)).
+		Where(squirrel.Eq{"id": params.ID}).ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql")
 		return
 	}
 
 	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if isUniqueViolation(err) {
+		return errs.ErrRoomExisted
+	} else if err != nil {
+		logger.Error().Err(err).Msg("failed to update room")
+		return
+	}
+
+	return
+}
+
+func (r *repository) FindRoomParticipant(ctx context.Context, params *indto.RoomParticipantParams) (res *model.RoomParticipant, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("id", "room_id", "user_id", "role", "muted_until").From("room_participants").Where(squirrel.And{
+		squirrel.Eq{"room_id": params.RoomID},
+		squirrel.Eq{"user_id": params.UserID},
+	}).ToSql()
 	if err != nil {
-		logger.Error().Err(err).Msg("faild to fetch room meta")
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.RoomParticipant{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch room participant")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+func (r *repository) FindRoomParticipants(ctx context.Context, params *indto.RoomParticipantParams) (res []*model.RoomParticipant, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("id", "room_id", "user_id", "role", "muted_until").From("room_participants").
+		Where(squirrel.Eq{"room_id": params.RoomID}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.RoomParticipant{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch room participants")
+		return
+	}
+
+	return
+}
+
+func (r *repository) InsertRoomParticipant(ctx context.Context, params *model.RoomParticipant) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("room_participants").Columns("room_id", "user_id", "role").
+		Values(params.RoomID, params.UserID, params.Role).Suffix("on conflict (room_id, user_id) do nothing").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert room participant")
+		return
+	}
+
+	return
+}
+
+func (r *repository) DeleteRoomParticipant(ctx context.Context, params *indto.RoomParticipantParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("room_participants").Where(squirrel.And{
+		squirrel.Eq{"room_id": params.RoomID},
+		squirrel.Eq{"user_id": params.UserID},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete room participant")
+		return
+	}
+
+	return
+}
+
+// UpdateRoomParticipant update role and mute state of a room member
+func (r *repository) UpdateRoomParticipant(ctx context.Context, params *model.RoomParticipant) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("room_participants").
+		Set("role", params.Role).
+		Set("muted_until", params.MutedUntil).
+		Where(squirrel.And{
+			squirrel.Eq{"room_id": params.RoomID},
+			squirrel.Eq{"user_id": params.UserID},
+		}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update room participant")
+		return
+	}
+
+	return
+}
+
+func (r *repository) FindRoomBan(ctx context.Context, params *indto.RoomParticipantParams) (res *model.RoomBan, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("id", "room_id", "user_id", "banned_by", "created_at").From("room_bans").Where(squirrel.And{
+		squirrel.Eq{"room_id": params.RoomID},
+		squirrel.Eq{"user_id": params.UserID},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.RoomBan{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch room ban")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+func (r *repository) InsertRoomBan(ctx context.Context, params *model.RoomBan) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("room_bans").Columns("room_id", "user_id", "banned_by", "created_at").
+		Values(params.RoomID, params.UserID, params.BannedBy, params.CreatedAt).
+		Suffix("on conflict (room_id, user_id) do nothing").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert room ban")
+		return
+	}
+
+	return
+}
+
+func (r *repository) DeleteRoomBan(ctx context.Context, params *indto.RoomParticipantParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("room_bans").Where(squirrel.And{
+		squirrel.Eq{"room_id": params.RoomID},
+		squirrel.Eq{"user_id": params.UserID},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete room ban")
 		return
 	}
 
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/nmluci/realtime-chat-sys/pkg/errs"
+)
+
+func TestFindRoom(t *testing.T) {
+	repo := newTestRepo(t)
+	owner := createTestUser(t, repo, "alice")
+	room := &model.ChatRoom{RoomName: "general", CreatedBy: owner.ID, Visibility: "private"}
+	roomID := createTestRoom(t, repo, room)
+
+	room.ID, room.InviteCode = roomID, "abc123"
+	if err := repo.UpdateRoom(testContext(), room); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params *indto.ChatRoomParams
+		found  bool
+	}{
+		{name: "by id", params: &indto.ChatRoomParams{ID: roomID}, found: true},
+		{name: "by name", params: &indto.ChatRoomParams{RoomName: "general"}, found: true},
+		{name: "by invite code", params: &indto.ChatRoomParams{InviteCode: "abc123"}, found: true},
+		{name: "unknown name", params: &indto.ChatRoomParams{RoomName: "random"}},
+		{name: "without filter", params: &indto.ChatRoomParams{}},
+		{name: "only user filter", params: &indto.ChatRoomParams{UserID: owner.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room, err := repo.FindRoom(testContext(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.found {
+				if room != nil {
+					t.Fatalf("expected no room, got %q", room.RoomName)
+				}
+				return
+			}
+
+			if room == nil || room.ID != roomID {
+				t.Fatalf("expected room %d, got %+v", roomID, room)
+			}
+		})
+	}
+}
+
+func TestRoomNameUnique(t *testing.T) {
+	repo := newTestRepo(t)
+	owner := createTestUser(t, repo, "alice")
+	createTestRoom(t, repo, &model.ChatRoom{RoomName: "general", CreatedBy: owner.ID})
+	randomID := createTestRoom(t, repo, &model.ChatRoom{RoomName: "random", CreatedBy: owner.ID})
+
+	if _, err := repo.CreateRoom(testContext(), &model.ChatRoom{RoomName: "general", CreatedBy: owner.ID}); !errors.Is(err, errs.ErrRoomExisted) {
+		t.Fatalf("expected room existed on create, got %v", err)
+	}
+
+	err := repo.UpdateRoom(testContext(), &model.ChatRoom{ID: randomID, RoomName: "general", CreatedBy: owner.ID})
+	if !errors.Is(err, errs.ErrRoomExisted) {
+		t.Fatalf("expected room existed on rename, got %v", err)
+	}
+}
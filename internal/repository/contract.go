@@ -6,24 +6,102 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/nmluci/realtime-chat-sys/internal/indto"
 	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
 )
 
 type Repository interface {
+	// RunInTx run fn against repository bound to a single transaction, it is committed only when fn succeed.
+	// Calling it again within fn reuse the transaction in progress
+	RunInTx(ctx context.Context, fn func(Repository) error) error
+
 	// ----- Users
 	FindUser(context.Context, *indto.UserParams) (*model.User, error)
 	InsertUser(context.Context, *model.User) error
+	FindUsers(context.Context, *indto.UserParams) ([]*model.User, error)
+	UpdateUserLastSeen(context.Context, *model.User) error
+	FindUserContacts(context.Context, *indto.UserParams) ([]int64, error)
 
-	// ----- Users
+	// ----- Sessions
+	InsertUserSession(context.Context, *model.UserSession) error
+	FindActiveUserSession(context.Context, *indto.UserSessionParams) (*model.UserSession, error)
+	RevokeUserSession(context.Context, *indto.UserSessionParams) error
+
+	// ----- Rooms
+	FindRooms(context.Context, *indto.ChatRoomParams) ([]*model.ChatRoom, error)
 	FindRoom(context.Context, *indto.ChatRoomParams) (*model.ChatRoom, error)
-	CreateRoom(context.Context, *model.ChatRoom) error
+	FindRoomDirectory(context.Context, *indto.RoomDirectoryParams) ([]*model.RoomDirectoryEntry, error)
+	CreateRoom(context.Context, *model.ChatRoom) (int64, error)
+	UpdateRoom(context.Context, *model.ChatRoom) error
+	FindRoomParticipant(context.Context, *indto.RoomParticipantParams) (*model.RoomParticipant, error)
+	FindRoomParticipants(context.Context, *indto.RoomParticipantParams) ([]*model.RoomParticipant, error)
+	InsertRoomParticipant(context.Context, *model.RoomParticipant) error
+	DeleteRoomParticipant(context.Context, *indto.RoomParticipantParams) error
+	UpdateRoomParticipant(context.Context, *model.RoomParticipant) error
+	FindRoomBan(context.Context, *indto.RoomParticipantParams) (*model.RoomBan, error)
+	InsertRoomBan(context.Context, *model.RoomBan) error
+	DeleteRoomBan(context.Context, *indto.RoomParticipantParams) error
+
+	// ----- Invites
+	InsertRoomInvite(context.Context, *model.RoomInvite) error
+	FindRoomInvite(context.Context, *indto.RoomInviteParams) (*model.RoomInvite, error)
+	FindRoomInvites(context.Context, *indto.RoomInviteParams) ([]*model.RoomInvite, error)
+	DeleteRoomInvite(context.Context, *indto.RoomInviteParams) error
+
+	// ----- Conversations
+	InsertConversation(context.Context, *model.Conversation) (int64, error)
+	FindConversation(context.Context, *indto.ConversationParams) (*model.Conversation, error)
+	FindConversations(context.Context, *indto.ConversationParams) ([]*model.Conversation, error)
+	FindConversationParticipants(context.Context, *indto.ConversationParams) ([]*model.ConversationParticipant, error)
 
 	// ----- Message
 	FindChatHistory(context.Context, *indto.ChatHistoryParams) ([]*model.ChatHistory, error)
-	InsertChatHistory(context.Context, *model.ChatHistory) error
+	FindChatMessage(context.Context, *indto.ChatHistoryParams) (*model.ChatHistory, error)
+	InsertChatHistory(context.Context, *model.ChatHistory) (int64, error)
+	UpdateChatHistory(context.Context, *model.ChatHistory) error
+	DeleteChatHistory(context.Context, *model.ChatHistory) error
+	SearchChatHistory(context.Context, *indto.MessageSearchParams) ([]*model.MessageSearchResult, error)
+
+	// ----- Attachments
+	InsertAttachment(context.Context, *model.Attachment) (int64, error)
+	FindAttachment(context.Context, *indto.AttachmentParams) (*model.Attachment, error)
+	FindAttachments(context.Context, *indto.AttachmentParams) ([]*model.Attachment, error)
+	LinkAttachments(context.Context, *indto.AttachmentParams) error
+	DeleteStaleAttachments(context.Context, *indto.AttachmentParams) error
+
+	// ----- Blobs
+	UpsertBlob(context.Context, *model.Blob) (bool, error)
+	FindUnreferencedBlobs(context.Context, *indto.BlobParams) ([]*model.Blob, error)
+	MarkBlobDeleted(context.Context, *indto.BlobParams) (*model.Blob, error)
+	DeleteBlob(context.Context, *indto.BlobParams) error
+
+	// ----- Mentions
+	InsertMentions(context.Context, []*model.Mention) error
+	FindMentions(context.Context, *indto.MentionParams) ([]*model.MentionedMessage, error)
+
+	// ----- Delivery
+	InsertMessageDelivery(context.Context, *model.MessageDelivery) error
+	FindUndeliveredMessages(context.Context, *indto.MessageDeliveryParams) ([]*model.ChatHistory, error)
+	MarkMessageDelivered(context.Context, *indto.MessageDeliveryParams) error
+
+	// ----- Reactions
+	InsertMessageReaction(context.Context, *model.MessageReaction) error
+	DeleteMessageReaction(context.Context, *indto.MessageReactionParams) error
+	FindReactionCounts(context.Context, *indto.MessageReactionParams) ([]*model.ReactionCount, error)
+
+	// ----- Receipts
+	UpsertReadMarker(context.Context, *model.ReadMarker) error
+	FindUnreadCounts(context.Context, *indto.ReadMarkerParams) ([]*model.UnreadCount, error)
+}
+
+// sqliteConn is satisfied by both the database and a transaction in progress
+type sqliteConn interface {
+	sqlx.ExtContext
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
 }
 
 type repository struct {
-	sqliteDB *sqlx.DB
+	sqliteDB sqliteConn
+	db       *sqlx.DB // nil when the repository is bound to a transaction
 }
 
 type NewRepositoryParams struct {
@@ -33,5 +111,36 @@ type NewRepositoryParams struct {
 func NewRepository(params *NewRepositoryParams) Repository {
 	return &repository{
 		sqliteDB: params.SQLiteDB,
+		db:       params.SQLiteDB,
 	}
 }
+
+func (r *repository) RunInTx(ctx context.Context, fn func(Repository) error) error {
+	return r.inTx(ctx, func(tx *repository) error { return fn(tx) })
+}
+
+func (r *repository) inTx(ctx context.Context, fn func(*repository) error) (err error) {
+	if r.db == nil {
+		return fn(r)
+	}
+
+	logger := zerolog.Ctx(ctx)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	if err = fn(&repository{sqliteDB: tx}); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	return
+}
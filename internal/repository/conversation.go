@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// InsertConversation create conversation along with its participants, existing conversation with the same participant set is reused
+func (r *repository) InsertConversation(ctx context.Context, params *model.Conversation) (id int64, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	err = r.inTx(ctx, func(tx *repository) (err error) {
+		stmt, args, err := squirrel.Insert("conversations").Columns("participant_key", "created_by", "created_at").
+			Values(params.ParticipantKey, params.CreatedBy, params.CreatedAt).
+			Suffix("on conflict (participant_key) do nothing").ToSql()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to generate sql")
+			return
+		}
+
+		if _, err = tx.sqliteDB.ExecContext(ctx, stmt, args...); err != nil {
+			logger.Error().Err(err).Msg("failed to insert conversation")
+			return
+		}
+
+		stmt, args, err = squirrel.Select("id").From("conversations").Where(squirrel.Eq{"participant_key": params.ParticipantKey}).ToSql()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to generate sql")
+			return
+		}
+
+		if err = tx.sqliteDB.QueryRowxContext(ctx, stmt, args...).Scan(&id); err != nil {
+			logger.Error().Err(err).Msg("failed to fetch inserted conversation id")
+			return
+		}
+
+		query := squirrel.Insert("conversation_participants").Columns("conversation_id", "user_id")
+		for _, p := range params.Participants {
+			query = query.Values(id, p.UserID)
+		}
+
+		stmt, args, err = query.Suffix("on conflict (user_id, conversation_id) do nothing").ToSql()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to generate sql")
+			return
+		}
+
+		if _, err = tx.sqliteDB.ExecContext(ctx, stmt, args...); err != nil {
+			logger.Error().Err(err).Msg("failed to insert conversation participants")
+			return
+		}
+
+		return
+	})
+
+	return
+}
+
+func (r *repository) FindConversation(ctx context.Context, params *indto.ConversationParams) (res *model.Conversation, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{}
+	if params.ID != 0 {
+		cond = append(cond, squirrel.Eq{"id": params.ID})
+	}
+
+	if params.ParticipantKey != "" {
+		cond = append(cond, squirrel.Eq{"participant_key": params.ParticipantKey})
+	}
+
+	stmt, args, err := squirrel.Select("id", "participant_key", "created_by", "created_at").From("conversations").
+		Where(cond).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.Conversation{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch conversation")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+// FindConversations fetch conversations the user participate in, ordered by the latest activity
+func (r *repository) FindConversations(ctx context.Context, params *indto.ConversationParams) (res []*model.Conversation, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("c.id", "c.participant_key", "c.created_by", "c.created_at").From("conversations c").
+		Join("conversation_participants cp on cp.conversation_id = c.id").
+		Where(squirrel.Eq{"cp.user_id": params.UserID}).
+		OrderBy("coalesce((select max(ch.id) from chat_histories ch where ch.conversation_id = c.id), 0) desc", "c.id desc").
+		ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.Conversation{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch conversations")
+		return
+	}
+
+	return
+}
+
+func (r *repository) FindConversationParticipants(ctx context.Context, params *indto.ConversationParams) (res []*model.ConversationParticipant, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{}
+	if params.ID != 0 {
+		cond = append(cond, squirrel.Eq{"cp.conversation_id": params.ID})
+	}
+
+	if len(params.ConversationIDs) != 0 {
+		cond = append(cond, squirrel.Eq{"cp.conversation_id": params.ConversationIDs})
+	}
+
+	stmt, args, err := squirrel.Select("cp.id", "cp.conversation_id", "cp.user_id", "u.username").From("conversation_participants cp").
+		Join("users u on u.id = cp.user_id").
+		Where(cond).
+		OrderBy("cp.conversation_id", "u.username").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.ConversationParticipant{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch conversation participants")
+		return
+	}
+
+	return
+}
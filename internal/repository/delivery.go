@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertMessageDelivery(ctx context.Context, params *model.MessageDelivery) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("message_deliveries").Columns("message_id", "recipient_id", "delivered_at", "created_at").
+		Values(params.MessageID, params.RecipientID, params.DeliveredAt, params.CreatedAt).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert message delivery")
+		return
+	}
+
+	return
+}
+
+// FindUndeliveredMessages fetch queued message of the recipient ordered from the oldest, up to the limit when given
+func (r *repository) FindUndeliveredMessages(ctx context.Context, params *indto.MessageDeliveryParams) (res []*model.ChatHistory, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	query := squirrel.Select("ch.id", "ch.room_id", "ch.sender_id", "coalesce(su.username, '') sender_name", "ch.recipient_id", "ch.message", "ch.message_type", "ch.message_meta", "ch.created_at", "ch.edited_at", "ch.deleted_at", "ch.parent_id", "ch.conversation_id").
+		From("message_deliveries md").
+		Join("chat_histories ch on ch.id = md.message_id").
+		LeftJoin("users su on ch.sender_id = su.id").
+		Where(squirrel.And{
+			squirrel.Eq{"md.recipient_id": params.RecipientID},
+			squirrel.Eq{"md.delivered_at": nil},
+		}).
+		OrderBy("ch.id asc")
+
+	if params.Limit != 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	stmt, args, err := query.ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.ChatHistory{}
+
+	rows, err := r.sqliteDB.QueryxContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch undelivered message")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		temp := &model.ChatHistory{}
+
+		if err = rows.StructScan(temp); err != nil {
+			logger.Error().Err(err).Msg("failed to map row result")
+			return
+		}
+
+		res = append(res, temp)
+	}
+
+	return
+}
+
+func (r *repository) MarkMessageDelivered(ctx context.Context, params *indto.MessageDeliveryParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("message_deliveries").Set("delivered_at", time.Now()).Where(squirrel.And{
+		squirrel.Eq{"recipient_id": params.RecipientID},
+		squirrel.Eq{"message_id": params.MessageIDs},
+		squirrel.Eq{"delivered_at": nil},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update message delivery")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertRoomInvite(ctx context.Context, params *model.RoomInvite) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("room_invites").Columns("room_id", "user_id", "invited_by", "created_at").
+		Values(params.RoomID, params.UserID, params.InvitedBy, params.CreatedAt).
+		Suffix("on conflict (user_id, room_id) do nothing").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert room invite")
+		return
+	}
+
+	return
+}
+
+func (r *repository) FindRoomInvite(ctx context.Context, params *indto.RoomInviteParams) (res *model.RoomInvite, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := r.roomInviteQuery().Where(squirrel.And{
+		squirrel.Eq{"ri.room_id": params.RoomID},
+		squirrel.Eq{"ri.user_id": params.UserID},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.RoomInvite{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch room invite")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+// FindRoomInvites fetch pending invites of the user, ordered from the newest
+func (r *repository) FindRoomInvites(ctx context.Context, params *indto.RoomInviteParams) (res []*model.RoomInvite, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := r.roomInviteQuery().Where(squirrel.Eq{"ri.user_id": params.UserID}).
+		OrderBy("ri.id desc").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.RoomInvite{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch room invites")
+		return
+	}
+
+	return
+}
+
+func (r *repository) DeleteRoomInvite(ctx context.Context, params *indto.RoomInviteParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("room_invites").Where(squirrel.And{
+		squirrel.Eq{"room_id": params.RoomID},
+		squirrel.Eq{"user_id": params.UserID},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete room invite")
+		return
+	}
+
+	return
+}
+
+func (r *repository) roomInviteQuery() squirrel.SelectBuilder {
+	return squirrel.Select("ri.id", "ri.room_id", "r.room_name", "r.visibility", "ri.user_id", "ri.invited_by", "coalesce(u.username, '') inviter_name", "ri.created_at").
+		From("room_invites ri").
+		Join("rooms r on r.id = ri.room_id").
+		LeftJoin("users u on u.id = ri.invited_by")
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertMentions(ctx context.Context, params []*model.Mention) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	if len(params) == 0 {
+		return
+	}
+
+	query := squirrel.Insert("mentions").Columns("message_id", "user_id", "is_room_mention", "created_at")
+	for _, m := range params {
+		query = query.Values(m.MessageID, m.UserID, m.IsRoomMention, m.CreatedAt)
+	}
+
+	stmt, args, err := query.Suffix("on conflict (user_id, message_id) do nothing").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert mentions")
+		return
+	}
+
+	return
+}
+
+// FindMentions fetch live messages mentioning the user ordered from the newest, room message is only listed
+// while the user is still a member or the room has one of the given visibilities
+func (r *repository) FindMentions(ctx context.Context, params *indto.MentionParams) (res []*model.MentionedMessage, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{
+		squirrel.Eq{"m.user_id": params.UserID},
+		squirrel.Eq{"ch.deleted_at": nil},
+		squirrel.Or{
+			squirrel.Eq{"ch.room_id": 0},
+			squirrel.Eq{"r.visibility": params.Visibilities},
+			squirrel.Expr("ch.room_id in (select rp.room_id from room_participants rp where rp.user_id = ?)", params.UserID),
+		},
+	}
+
+	if params.RoomID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.room_id": params.RoomID})
+	}
+
+	if params.BeforeID != 0 {
+		cond = append(cond, squirrel.Lt{"m.message_id": params.BeforeID})
+	}
+
+	query := squirrel.Select("ch.id", "ch.room_id", "coalesce(r.room_name, '') room_name", "ch.sender_id", "coalesce(su.username, '') sender_name", "ch.recipient_id", "coalesce(ru.username, '') recipient_name", "ch.message", "ch.message_type", "ch.message_meta", "ch.created_at", "ch.edited_at", "ch.deleted_at", "ch.parent_id", "ch.conversation_id", replyCountColumn, "m.is_room_mention").
+		From("mentions m").
+		Join("chat_histories ch on ch.id = m.message_id").
+		LeftJoin("users su on ch.sender_id = su.id").
+		LeftJoin("users ru on ch.recipient_id = ru.id and ch.recipient_id <> 0").
+		LeftJoin("rooms r on r.id = ch.room_id and ch.room_id <> 0").
+		Where(cond).
+		OrderBy("m.message_id desc")
+
+	if params.Limit != 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	stmt, args, err := query.ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.MentionedMessage{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch mentions")
+		return
+	}
+
+	return
+}
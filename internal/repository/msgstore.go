@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"slices"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/nmluci/realtime-chat-sys/internal/indto"
@@ -9,22 +11,31 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func (r *repository) InsertChatHistory(ctx context.Context, params *model.ChatHistory) (err error) {
+// replyCountColumn count live replies of each top level message
+const replyCountColumn = "(select count(t.id) from chat_histories t where t.parent_id = ch.id and t.deleted_at is null) reply_count"
+
+func (r *repository) InsertChatHistory(ctx context.Context, params *model.ChatHistory) (id int64, err error) {
 	logger := zerolog.Ctx(ctx)
 
-	stmt, args, err := squirrel.Insert("chat_histories").Columns("room_id", "sender_id", "recipient_id", "message").
-		Values(params.RoomID, params.SenderID, params.RecipientID, params.Message).ToSql()
+	stmt, args, err := squirrel.Insert("chat_histories").Columns("room_id", "sender_id", "recipient_id", "message", "message_type", "message_meta", "created_at", "parent_id", "conversation_id").
+		Values(params.RoomID, params.SenderID, params.RecipientID, params.Message, params.MessageType, params.MessageMeta, params.CreatedAt, params.ParentID, params.ConversationID).ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql")
 		return
 	}
 
-	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	res, err := r.sqliteDB.ExecContext(ctx, stmt, args...)
 	if err != nil {
 		logger.Error().Err(err).Msg("faild to insert chat")
 		return
 	}
 
+	id, err = res.LastInsertId()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch inserted chat id")
+		return
+	}
+
 	return
 }
 
@@ -32,28 +43,62 @@ func (r *repository) FindChatHistory(ctx context.Context, params *indto.ChatHist
 	logger := zerolog.Ctx(ctx)
 
 	cond := squirrel.And{}
-	if params.RoomName != "" {
-		cond = append(cond, squirrel.Eq{"r.name": params.RoomName})
+	if params.ParentID != 0 {
+		// thread replies share the parent conversation, the parent alone define the scope
+		cond = append(cond, squirrel.Eq{"ch.parent_id": params.ParentID})
+	} else if params.ConversationID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.conversation_id": params.ConversationID})
+	} else if params.IsDM {
+		// direct message only have a recipient and no room, fetch both side of the conversation
+		cond = append(cond, squirrel.Eq{"ch.room_id": 0}, squirrel.Or{
+			squirrel.Eq{"ch.sender_id": params.UserID, "ch.recipient_id": params.PeerID},
+			squirrel.Eq{"ch.sender_id": params.PeerID, "ch.recipient_id": params.UserID},
+		})
+	} else {
+		if params.RoomName != "" {
+			cond = append(cond, squirrel.Eq{"r.room_name": params.RoomName})
+		}
+
+		if params.RoomID != 0 {
+			cond = append(cond, squirrel.Eq{"ch.room_id": params.RoomID})
+		}
+
+		if params.UserID != 0 {
+			cond = append(cond, squirrel.Eq{"ch.sender_id": params.UserID})
+		}
+	}
+
+	// thread replies are only listed within their thread
+	if params.ParentID == 0 {
+		cond = append(cond, squirrel.Eq{"ch.parent_id": 0})
 	}
 
-	if params.RoomID != 0 {
-		cond = append(cond, squirrel.Eq{"ch.room_id": params.RoomID})
+	if params.BeforeID != 0 {
+		cond = append(cond, squirrel.Lt{"ch.id": params.BeforeID})
 	}
 
-	if params.UserID != 0 {
-		cond = append(cond, squirrel.Eq{"ch.sender_id": params.UserID})
+	if params.AfterID != 0 {
+		cond = append(cond, squirrel.Gt{"ch.id": params.AfterID})
+	}
 
-		if params.RoomName != "" {
-			cond = append(cond, squirrel.Eq{"ch.recipient_id": params.UserID}, squirrel.Eq{"ch.room_id": 0})
-		}
+	// scan forward only when reading after a cursor, otherwise read backward from the newest message
+	orderBy := "ch.id desc"
+	if params.AfterID != 0 {
+		orderBy = "ch.id asc"
 	}
 
-	stmt, args, err := squirrel.Select("ch.id", "ch.room_id", "ch.sender_id", "su.username sender_name", "ch.recipient_id", "coalesce(ru.username, '') recipient_name", "ch.message").From("chat_histories ch").
+	query := squirrel.Select("ch.id", "ch.room_id", "coalesce(r.room_name, '') room_name", "ch.sender_id", "coalesce(su.username, '') sender_name", "ch.recipient_id", "coalesce(ru.username, '') recipient_name", "ch.message", "ch.message_type", "ch.message_meta", "ch.created_at", "ch.edited_at", "ch.deleted_at", "ch.parent_id", "ch.conversation_id", replyCountColumn).From("chat_histories ch").
 		LeftJoin("users su on ch.sender_id = su.id").
-		LeftJoin("users ru on ch.recipient = ru.id and ch.recipient_id <> 0").
+		LeftJoin("users ru on ch.recipient_id = ru.id and ch.recipient_id <> 0").
 		LeftJoin("rooms r on r.id = ch.room_id and ch.room_id <> 0").
 		Where(cond).
-		ToSql()
+		OrderBy(orderBy)
+
+	if params.Limit != 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	stmt, args, err := query.ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql")
 		return
@@ -63,14 +108,15 @@ func (r *repository) FindChatHistory(ctx context.Context, params *indto.ChatHist
 
 	rows, err := r.sqliteDB.QueryxContext(ctx, stmt, args...)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to fetch room meta")
+		logger.Error().Err(err).Msg("failed to fetch chat history")
 		return
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		temp := &model.ChatHistory{}
 
-		if err = rows.StructScan(&temp); err != nil {
+		if err = rows.StructScan(temp); err != nil {
 			logger.Error().Err(err).Msg("failed to map row result")
 			return
 		}
@@ -78,6 +124,86 @@ func (r *repository) FindChatHistory(ctx context.Context, params *indto.ChatHist
 		res = append(res, temp)
 	}
 
+	// always return messages ordered from the oldest
+	if params.AfterID == 0 {
+		slices.Reverse(res)
+	}
+
+	return
+}
+
+func (r *repository) FindChatMessage(ctx context.Context, params *indto.ChatHistoryParams) (res *model.ChatHistory, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("ch.id", "ch.room_id", "coalesce(r.room_name, '') room_name", "ch.sender_id", "coalesce(su.username, '') sender_name", "ch.recipient_id", "coalesce(ru.username, '') recipient_name", "ch.message", "ch.message_type", "ch.message_meta", "ch.created_at", "ch.edited_at", "ch.deleted_at", "ch.parent_id", "ch.conversation_id", replyCountColumn).From("chat_histories ch").
+		LeftJoin("users su on ch.sender_id = su.id").
+		LeftJoin("users ru on ch.recipient_id = ru.id and ch.recipient_id <> 0").
+		LeftJoin("rooms r on r.id = ch.room_id and ch.room_id <> 0").
+		Where(squirrel.Eq{"ch.id": params.ID}).
+		ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = &model.ChatHistory{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch chat message")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+func (r *repository) UpdateChatHistory(ctx context.Context, params *model.ChatHistory) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("chat_histories").
+		Set("message", params.Message).
+		Set("edited_at", params.EditedAt).
+		Where(squirrel.And{
+			squirrel.Eq{"id": params.ID},
+			squirrel.Eq{"deleted_at": nil},
+		}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update chat")
+		return
+	}
+
 	return
+}
 
+// DeleteChatHistory leave a tombstone in place of the message so history and reply references stay intact
+func (r *repository) DeleteChatHistory(ctx context.Context, params *model.ChatHistory) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("chat_histories").
+		Set("message", "").
+		Set("message_meta", "").
+		Set("deleted_at", params.DeletedAt).
+		Where(squirrel.And{
+			squirrel.Eq{"id": params.ID},
+			squirrel.Eq{"deleted_at": nil},
+		}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete chat")
+		return
+	}
+
+	return
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertMessageReaction(ctx context.Context, params *model.MessageReaction) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("message_reactions").Columns("message_id", "user_id", "emoji", "created_at").
+		Values(params.MessageID, params.UserID, params.Emoji, params.CreatedAt).
+		Suffix("on conflict (message_id, user_id, emoji) do nothing").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert message reaction")
+		return
+	}
+
+	return
+}
+
+func (r *repository) DeleteMessageReaction(ctx context.Context, params *indto.MessageReactionParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Delete("message_reactions").Where(squirrel.And{
+		squirrel.Eq{"message_id": params.MessageID},
+		squirrel.Eq{"user_id": params.UserID},
+		squirrel.Eq{"emoji": params.Emoji},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete message reaction")
+		return
+	}
+
+	return
+}
+
+// FindReactionCounts aggregate reactions per emoji of every requested message, ordered by first reaction of each emoji
+func (r *repository) FindReactionCounts(ctx context.Context, params *indto.MessageReactionParams) (res []*model.ReactionCount, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	res = []*model.ReactionCount{}
+	if len(params.MessageIDs) == 0 {
+		return
+	}
+
+	stmt, args, err := squirrel.Select("message_id", "emoji", "count(id) count").From("message_reactions").
+		Where(squirrel.Eq{"message_id": params.MessageIDs}).
+		GroupBy("message_id", "emoji").
+		OrderBy("message_id", "min(id)").
+		ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch reaction count")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// UpsertReadMarker move read marker forward, marker is never moved backward
+func (r *repository) UpsertReadMarker(ctx context.Context, params *model.ReadMarker) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("read_markers").Columns("user_id", "room_id", "peer_id", "conversation_id", "message_id", "updated_at").
+		Values(params.UserID, params.RoomID, params.PeerID, params.ConversationID, params.MessageID, params.UpdatedAt).
+		Suffix("on conflict (user_id, room_id, peer_id, conversation_id) do update set message_id = max(message_id, excluded.message_id), updated_at = excluded.updated_at").
+		ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to upsert read marker")
+		return
+	}
+
+	return
+}
+
+// FindUnreadCounts count unread message per joined room, DM peer and group conversation, conversation without unread message is omitted.
+// Thread replies are left out just like in the history the read marker follow
+func (r *repository) FindUnreadCounts(ctx context.Context, params *indto.ReadMarkerParams) (res []*model.UnreadCount, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	roomQuery := squirrel.Select("rp.room_id", "r.room_name", "0 peer_id", "'' peer_name", "0 conversation_id", "count(ch.id) unread").From("room_participants rp").
+		Join("rooms r on r.id = rp.room_id").
+		LeftJoin("read_markers rm on rm.user_id = rp.user_id and rm.room_id = rp.room_id and rm.peer_id = 0 and rm.conversation_id = 0").
+		Join("chat_histories ch on ch.room_id = rp.room_id and ch.id > coalesce(rm.message_id, 0) and ch.sender_id <> rp.user_id and ch.deleted_at is null and ch.parent_id = 0").
+		Where(squirrel.Eq{"rp.user_id": params.UserID}).
+		GroupBy("rp.room_id", "r.room_name")
+
+	dmQuery := squirrel.Select("0 room_id", "'' room_name", "ch.sender_id peer_id", "su.username peer_name", "0 conversation_id", "count(ch.id) unread").From("chat_histories ch").
+		Join("users su on su.id = ch.sender_id").
+		LeftJoin("read_markers rm on rm.user_id = ch.recipient_id and rm.room_id = 0 and rm.peer_id = ch.sender_id and rm.conversation_id = 0").
+		Where(squirrel.And{
+			squirrel.Eq{"ch.recipient_id": params.UserID},
+			squirrel.Eq{"ch.room_id": 0},
+			squirrel.NotEq{"ch.sender_id": params.UserID},
+			squirrel.Expr("ch.id > coalesce(rm.message_id, 0)"),
+			squirrel.Eq{"ch.deleted_at": nil},
+			squirrel.Eq{"ch.parent_id": 0},
+		}).
+		GroupBy("ch.sender_id", "su.username")
+
+	conversationQuery := squirrel.Select("0 room_id", "'' room_name", "0 peer_id", "'' peer_name", "cp.conversation_id", "count(ch.id) unread").From("conversation_participants cp").
+		LeftJoin("read_markers rm on rm.user_id = cp.user_id and rm.room_id = 0 and rm.peer_id = 0 and rm.conversation_id = cp.conversation_id").
+		Join("chat_histories ch on ch.conversation_id = cp.conversation_id and ch.id > coalesce(rm.message_id, 0) and ch.sender_id <> cp.user_id and ch.deleted_at is null and ch.parent_id = 0").
+		Where(squirrel.Eq{"cp.user_id": params.UserID}).
+		GroupBy("cp.conversation_id")
+
+	res = []*model.UnreadCount{}
+	for _, query := range []squirrel.SelectBuilder{roomQuery, dmQuery, conversationQuery} {
+		stmt, args, err := query.ToSql()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to generate sql")
+			return nil, err
+		}
+
+		temp := []*model.UnreadCount{}
+		if err = r.sqliteDB.SelectContext(ctx, &temp, stmt, args...); err != nil {
+			logger.Error().Err(err).Msg("failed to fetch unread count")
+			return nil, err
+		}
+
+		res = append(res, temp...)
+	}
+
+	return
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+	_ "modernc.org/sqlite"
+)
+
+func TestMain(m *testing.M) {
+	// match the server, stored timestamps are compared as text in local time
+	time.Local = time.UTC
+
+	os.Exit(m.Run())
+}
+
+// newTestRepo open a migrated sqlite database which is removed once the test finished
+func newTestRepo(t *testing.T) Repository {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	db, err := sqlx.Connect("sqlite", fmt.Sprintf("%s?_time_format=sqlite&_txlock=immediate&_pragma=busy_timeout(5000)", dbPath))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dbMigrate, err := migrate.New("file://../../migrations", fmt.Sprintf("sqlite://%s", filepath.ToSlash(dbPath)))
+	if err != nil {
+		t.Fatalf("failed to init migration: %v", err)
+	}
+	defer dbMigrate.Close()
+
+	if err = dbMigrate.Up(); err != nil {
+		t.Fatalf("failed to migrate db: %v", err)
+	}
+
+	return NewRepository(&NewRepositoryParams{SQLiteDB: db})
+}
+
+func testContext() context.Context {
+	return zerolog.Nop().WithContext(context.Background())
+}
+
+func createTestUser(t *testing.T, repo Repository, username string) *model.User {
+	t.Helper()
+
+	if err := repo.InsertUser(testContext(), &model.User{Username: username, Password: "-"}); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	user, err := repo.FindUser(testContext(), &indto.UserParams{Username: username})
+	if err != nil || user == nil {
+		t.Fatalf("failed to fetch user %s: %v", username, err)
+	}
+
+	return user
+}
+
+func createTestRoom(t *testing.T, repo Repository, room *model.ChatRoom) int64 {
+	t.Helper()
+
+	id, err := repo.CreateRoom(testContext(), room)
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	return id
+}
+
+func TestRunInTx(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	errAbort := errors.New("abort")
+
+	err := repo.RunInTx(ctx, func(tx Repository) error {
+		if err := tx.InsertUser(ctx, &model.User{Username: "alice", Password: "-"}); err != nil {
+			return err
+		}
+
+		// nested call join the transaction in progress instead of starting another one
+		return tx.RunInTx(ctx, func(nested Repository) error {
+			if err := nested.InsertUser(ctx, &model.User{Username: "bob", Password: "-"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	users, err := repo.FindUsers(ctx, &indto.UserParams{Usernames: []string{"alice", "bob"}})
+	if err != nil || len(users) != 0 {
+		t.Fatalf("rolled back transaction must not leave users behind, got %d err %v", len(users), err)
+	}
+
+	err = repo.RunInTx(ctx, func(tx Repository) error {
+		return tx.InsertUser(ctx, &model.User{Username: "alice", Password: "-"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user, err := repo.FindUser(ctx, &indto.UserParams{Username: "alice"}); err != nil || user == nil {
+		t.Fatalf("committed user is missing: %v", err)
+	}
+}
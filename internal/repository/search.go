@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// snippetColumn highlight matched terms within roughly 16 tokens around the match, see model.SnippetMatchStart
+const snippetColumn = "snippet(chat_histories_fts, 0, char(2), char(3), '…', 16) snippet"
+
+// SearchChatHistory run full-text query over live messages the user has access to, ordered from the newest
+func (r *repository) SearchChatHistory(ctx context.Context, params *indto.MessageSearchParams) (res []*model.MessageSearchResult, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{
+		squirrel.Expr("chat_histories_fts match ?", params.Match),
+		squirrel.Eq{"ch.deleted_at": nil},
+		squirrel.Or{
+			squirrel.Expr("ch.room_id in (select rp.room_id from room_participants rp where rp.user_id = ?)", params.UserID),
+			squirrel.Expr("ch.conversation_id in (select cp.conversation_id from conversation_participants cp where cp.user_id = ?)", params.UserID),
+			squirrel.And{
+				squirrel.Eq{"ch.room_id": 0, "ch.conversation_id": 0},
+				squirrel.Or{squirrel.Eq{"ch.sender_id": params.UserID}, squirrel.Eq{"ch.recipient_id": params.UserID}},
+			},
+		},
+	}
+
+	if params.RoomID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.room_id": params.RoomID})
+	}
+
+	if params.PeerID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.room_id": 0, "ch.conversation_id": 0}, squirrel.Or{
+			squirrel.Eq{"ch.sender_id": params.UserID, "ch.recipient_id": params.PeerID},
+			squirrel.Eq{"ch.sender_id": params.PeerID, "ch.recipient_id": params.UserID},
+		})
+	}
+
+	if params.ConversationID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.conversation_id": params.ConversationID})
+	}
+
+	if params.SenderID != 0 {
+		cond = append(cond, squirrel.Eq{"ch.sender_id": params.SenderID})
+	}
+
+	if params.Since != nil {
+		cond = append(cond, squirrel.GtOrEq{"ch.created_at": params.Since})
+	}
+
+	if params.Until != nil {
+		cond = append(cond, squirrel.Lt{"ch.created_at": params.Until})
+	}
+
+	if params.BeforeID != 0 {
+		cond = append(cond, squirrel.Lt{"ch.id": params.BeforeID})
+	}
+
+	query := squirrel.Select("ch.id", "ch.room_id", "coalesce(r.room_name, '') room_name", "ch.sender_id", "coalesce(su.username, '') sender_name", "ch.recipient_id", "coalesce(ru.username, '') recipient_name", "ch.message", "ch.message_type", "ch.message_meta", "ch.created_at", "ch.edited_at", "ch.deleted_at", "ch.parent_id", "ch.conversation_id", replyCountColumn, snippetColumn).
+		From("chat_histories_fts").
+		Join("chat_histories ch on ch.id = chat_histories_fts.rowid").
+		LeftJoin("users su on ch.sender_id = su.id").
+		LeftJoin("users ru on ch.recipient_id = ru.id and ch.recipient_id <> 0").
+		LeftJoin("rooms r on r.id = ch.room_id and ch.room_id <> 0").
+		Where(cond).
+		OrderBy("ch.id desc")
+
+	if params.Limit != 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	stmt, args, err := query.ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql")
+		return
+	}
+
+	res = []*model.MessageSearchResult{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to search chat history")
+		return
+	}
+
+	return
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+	"github.com/rs/zerolog"
+)
+
+func (r *repository) InsertUserSession(ctx context.Context, params *model.UserSession) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Insert("user_sessions").Columns("id", "user_id", "expires_at", "created_at").
+		Values(params.ID, params.UserID, params.ExpiresAt, params.CreatedAt).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to insert user session")
+		return
+	}
+
+	return
+}
+
+// FindActiveUserSession only return session which is neither expired nor revoked
+func (r *repository) FindActiveUserSession(ctx context.Context, params *indto.UserSessionParams) (res *model.UserSession, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("id", "user_id", "expires_at", "revoked_at", "created_at").From("user_sessions").Where(squirrel.And{
+		squirrel.Eq{"id": params.ID},
+		squirrel.Eq{"revoked_at": nil},
+		squirrel.Gt{"expires_at": time.Now()},
+	}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	res = &model.UserSession{}
+	err = r.sqliteDB.QueryRowxContext(ctx, stmt, args...).StructScan(res)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error().Err(err).Msg("failed to fetch user session")
+		return
+	} else if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return
+}
+
+func (r *repository) RevokeUserSession(ctx context.Context, params *indto.UserSessionParams) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.And{squirrel.Eq{"revoked_at": nil}}
+	if params.ID != "" {
+		cond = append(cond, squirrel.Eq{"id": params.ID})
+	}
+
+	if params.UserID != 0 {
+		cond = append(cond, squirrel.Eq{"user_id": params.UserID})
+	}
+
+	stmt, args, err := squirrel.Update("user_sessions").Set("revoked_at", time.Now()).Where(cond).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to revoke user session")
+		return
+	}
+
+	return
+}
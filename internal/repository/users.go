@@ -13,9 +13,14 @@ import (
 func (r *repository) FindUser(ctx context.Context, params *indto.UserParams) (res *model.User, err error) {
 	logger := zerolog.Ctx(ctx)
 
-	stmt, args, err := squirrel.Select("id", "username", "password").From("users").Where(squirrel.And{
-		squirrel.Eq{"username": params.Username},
-	}).ToSql()
+	cond := squirrel.And{}
+	if params.ID != 0 {
+		cond = append(cond, squirrel.Eq{"id": params.ID})
+	} else {
+		cond = append(cond, squirrel.Eq{"username": params.Username})
+	}
+
+	stmt, args, err := squirrel.Select("id", "username", "password", "last_seen_at").From("users").Where(cond).ToSql()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to generate sql stmt")
 		return
@@ -51,3 +56,84 @@ func (r *repository) InsertUser(ctx context.Context, params *model.User) (err er
 
 	return
 }
+
+// FindUsers fetch usermeta by either list of usernames or ids
+func (r *repository) FindUsers(ctx context.Context, params *indto.UserParams) (res []*model.User, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	cond := squirrel.Or{}
+	if len(params.Usernames) != 0 {
+		cond = append(cond, squirrel.Eq{"username": params.Usernames})
+	}
+
+	if len(params.IDs) != 0 {
+		cond = append(cond, squirrel.Eq{"id": params.IDs})
+	}
+
+	res = []*model.User{}
+	if len(cond) == 0 {
+		return
+	}
+
+	stmt, args, err := squirrel.Select("id", "username", "last_seen_at").From("users").Where(cond).OrderBy("id").ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch userdata")
+		return
+	}
+
+	return
+}
+
+func (r *repository) UpdateUserLastSeen(ctx context.Context, params *model.User) (err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Update("users").Set("last_seen_at", params.LastSeenAt).
+		Where(squirrel.Eq{"id": params.ID}).ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	_, err = r.sqliteDB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update userdata")
+		return
+	}
+
+	return
+}
+
+// FindUserContacts list every user sharing a room or group conversation with the user, or having DM with them
+func (r *repository) FindUserContacts(ctx context.Context, params *indto.UserParams) (res []int64, err error) {
+	logger := zerolog.Ctx(ctx)
+
+	stmt, args, err := squirrel.Select("rp.user_id").From("room_participants rp").
+		Join("room_participants me on me.room_id = rp.room_id and me.user_id = ?", params.ID).
+		Where(squirrel.NotEq{"rp.user_id": params.ID}).
+		Suffix(`union select cp.user_id
+			from conversation_participants cp
+			join conversation_participants me on me.conversation_id = cp.conversation_id and me.user_id = ?
+			where cp.user_id <> ?
+		union select case when ch.sender_id = ? then ch.recipient_id else ch.sender_id end
+			from chat_histories ch
+			where ch.room_id = 0 and ch.conversation_id = 0 and ch.sender_id <> ch.recipient_id and (ch.sender_id = ? or ch.recipient_id = ?)`,
+			params.ID, params.ID, params.ID, params.ID, params.ID).
+		ToSql()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate sql stmt")
+		return
+	}
+
+	res = []int64{}
+	if err = r.sqliteDB.SelectContext(ctx, &res, stmt, args...); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch user contacts")
+		return
+	}
+
+	return
+}
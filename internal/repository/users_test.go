@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nmluci/realtime-chat-sys/internal/indto"
+	"github.com/nmluci/realtime-chat-sys/internal/model"
+)
+
+func TestFindUserContacts(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := testContext()
+	now := time.Now()
+
+	users := map[string]*model.User{}
+	for _, name := range []string{"alice", "bob", "carol", "dave", "erin", "frank"} {
+		users[name] = createTestUser(t, repo, name)
+	}
+
+	join := func(roomID int64, names ...string) {
+		for _, name := range names {
+			if err := repo.InsertRoomParticipant(ctx, &model.RoomParticipant{RoomID: roomID, UserID: users[name].ID, Role: "member"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// bob share a room with alice while erin is only in another room
+	join(createTestRoom(t, repo, &model.ChatRoom{RoomName: "general", CreatedBy: users["alice"].ID, Visibility: "public"}), "alice", "bob")
+	join(createTestRoom(t, repo, &model.ChatRoom{RoomName: "random", CreatedBy: users["erin"].ID, Visibility: "public"}), "erin", "bob")
+
+	// carol had DM with alice
+	if _, err := repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: users["carol"].ID, RecipientID: users["alice"].ID, Message: "hi", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	// dave is in a group conversation with alice, no message were sent yet
+	_, err := repo.InsertConversation(ctx, &model.Conversation{
+		ParticipantKey: "alice,dave",
+		CreatedBy:      users["alice"].ID,
+		CreatedAt:      now,
+		Participants: []*model.ConversationParticipant{
+			{UserID: users["alice"].ID}, {UserID: users["dave"].ID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// message of a conversation alice isn't part of must not turn its sender into a contact
+	convID, err := repo.InsertConversation(ctx, &model.Conversation{
+		ParticipantKey: "erin,frank",
+		CreatedBy:      users["frank"].ID,
+		CreatedAt:      now,
+		Participants:   []*model.ConversationParticipant{{UserID: users["erin"].ID}, {UserID: users["frank"].ID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.InsertChatHistory(ctx, &model.ChatHistory{SenderID: users["frank"].ID, ConversationID: convID, Message: "hi", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	contacts, err := repo.FindUserContacts(ctx, &indto.UserParams{ID: users["alice"].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(contacts)
+
+	expected := []int64{users["bob"].ID, users["carol"].ID, users["dave"].ID}
+	if !slices.Equal(contacts, expected) {
+		t.Fatalf("expected contacts %v, got %v", expected, contacts)
+	}
+
+	contacts, err = repo.FindUserContacts(ctx, &indto.UserParams{ID: users["frank"].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(contacts)
+
+	// recipient of group message is 0, it must never be reported as contact
+	expected = []int64{users["erin"].ID}
+	if !slices.Equal(contacts, expected) {
+		t.Fatalf("expected contacts %v, got %v", expected, contacts)
+	}
+}
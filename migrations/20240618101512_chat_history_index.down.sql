@@ -0,0 +1,2 @@
+drop index idx_chat_histories_dm;
+drop index idx_chat_histories_room;
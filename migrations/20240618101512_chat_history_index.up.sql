@@ -0,0 +1,2 @@
+create index idx_chat_histories_room on chat_histories (room_id, id);
+create index idx_chat_histories_dm on chat_histories (sender_id, recipient_id, id);
@@ -0,0 +1 @@
+alter table chat_histories drop column created_at;
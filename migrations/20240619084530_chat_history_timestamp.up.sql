@@ -0,0 +1 @@
+alter table chat_histories add column created_at datetime not null default '1970-01-01 00:00:00';
@@ -0,0 +1,2 @@
+drop index idx_room_participants_user;
+drop index idx_room_participants_member;
@@ -0,0 +1,7 @@
+-- drop duplicate membership before enforcing uniqueness
+delete from room_participants where id not in (
+    select min(id) from room_participants group by room_id, user_id
+);
+
+create unique index idx_room_participants_member on room_participants (room_id, user_id);
+create index idx_room_participants_user on room_participants (user_id);
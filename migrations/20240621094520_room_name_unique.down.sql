@@ -0,0 +1 @@
+drop index idx_rooms_room_name;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- rename duplicated room before enforcing uniqueness, the oldest room keep its name
+update rooms set room_name = room_name || ' (' || id || ')' where id not in (
+    select min(id) from rooms group by room_name
+);
+
+create unique index idx_rooms_room_name on rooms (room_name);
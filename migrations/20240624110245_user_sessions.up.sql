@@ -0,0 +1,9 @@
+create table user_sessions (
+    id text primary key,
+    user_id integer not null,
+    expires_at datetime not null,
+    revoked_at datetime,
+    created_at datetime not null
+);
+
+create index idx_user_sessions_user on user_sessions (user_id);
@@ -0,0 +1 @@
+drop table message_deliveries;
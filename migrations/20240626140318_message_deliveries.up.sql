@@ -0,0 +1,10 @@
+create table message_deliveries (
+    id integer primary key,
+    message_id integer not null,
+    recipient_id integer not null,
+    delivered_at datetime,
+    created_at datetime not null
+);
+
+create unique index idx_message_deliveries_recipient on message_deliveries (recipient_id, message_id);
+create index idx_message_deliveries_pending on message_deliveries (recipient_id, delivered_at);
@@ -0,0 +1 @@
+drop table read_markers;
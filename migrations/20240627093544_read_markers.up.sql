@@ -0,0 +1,10 @@
+create table read_markers (
+    id integer primary key,
+    user_id integer not null,
+    room_id integer not null,
+    peer_id integer not null,
+    message_id integer not null,
+    updated_at datetime not null
+);
+
+create unique index idx_read_markers_conversation on read_markers (user_id, room_id, peer_id);
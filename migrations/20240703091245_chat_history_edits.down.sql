@@ -0,0 +1,2 @@
+alter table chat_histories drop column deleted_at;
+alter table chat_histories drop column edited_at;
@@ -0,0 +1,2 @@
+alter table chat_histories add column edited_at datetime;
+alter table chat_histories add column deleted_at datetime;
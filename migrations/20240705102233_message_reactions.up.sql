@@ -0,0 +1,9 @@
+create table message_reactions (
+    id integer primary key,
+    message_id integer not null,
+    user_id integer not null,
+    emoji text not null,
+    created_at datetime not null
+);
+
+create unique index idx_message_reactions_user on message_reactions (message_id, user_id, emoji);
@@ -0,0 +1,3 @@
+drop index idx_chat_histories_parent;
+
+alter table chat_histories drop column parent_id;
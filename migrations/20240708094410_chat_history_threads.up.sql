@@ -0,0 +1,3 @@
+alter table chat_histories add column parent_id integer not null default 0;
+
+create index idx_chat_histories_parent on chat_histories (parent_id, id);
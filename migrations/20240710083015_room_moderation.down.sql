@@ -0,0 +1,6 @@
+drop table room_bans;
+
+alter table room_participants drop column muted_until;
+alter table room_participants drop column role;
+
+alter table rooms drop column created_by;
@@ -0,0 +1,14 @@
+alter table rooms add column created_by integer not null default 0;
+
+alter table room_participants add column role text not null default 'member';
+alter table room_participants add column muted_until datetime;
+
+create table room_bans (
+    id integer primary key,
+    room_id integer not null,
+    user_id integer not null,
+    banned_by integer not null,
+    created_at datetime not null
+);
+
+create unique index idx_room_bans_user on room_bans (room_id, user_id);
@@ -0,0 +1,6 @@
+drop table room_invites;
+
+drop index idx_rooms_invite_code;
+
+alter table rooms drop column invite_code;
+alter table rooms drop column visibility;
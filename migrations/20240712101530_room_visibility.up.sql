@@ -0,0 +1,14 @@
+alter table rooms add column visibility text not null default 'public';
+alter table rooms add column invite_code text;
+
+create unique index idx_rooms_invite_code on rooms (invite_code);
+
+create table room_invites (
+    id integer primary key,
+    room_id integer not null,
+    user_id integer not null,
+    invited_by integer not null,
+    created_at datetime not null
+);
+
+create unique index idx_room_invites_user on room_invites (user_id, room_id);
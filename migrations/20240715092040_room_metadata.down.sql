@@ -0,0 +1,3 @@
+alter table rooms drop column created_at;
+alter table rooms drop column description;
+alter table rooms drop column topic;
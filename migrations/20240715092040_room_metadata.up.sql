@@ -0,0 +1,3 @@
+alter table rooms add column topic text not null default '';
+alter table rooms add column description text not null default '';
+alter table rooms add column created_at datetime;
@@ -0,0 +1,13 @@
+delete from read_markers where conversation_id <> 0;
+
+drop index idx_read_markers_conversation;
+create unique index idx_read_markers_conversation on read_markers (user_id, room_id, peer_id);
+
+alter table read_markers drop column conversation_id;
+
+drop index idx_chat_histories_conversation;
+
+alter table chat_histories drop column conversation_id;
+
+drop table conversation_participants;
+drop table conversations;
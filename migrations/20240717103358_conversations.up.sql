@@ -0,0 +1,25 @@
+create table conversations (
+    id integer primary key,
+    participant_key text not null,
+    created_by integer not null,
+    created_at datetime not null
+);
+
+create unique index idx_conversations_participant_key on conversations (participant_key);
+
+create table conversation_participants (
+    id integer primary key,
+    conversation_id integer not null,
+    user_id integer not null
+);
+
+create unique index idx_conversation_participants_user on conversation_participants (user_id, conversation_id);
+
+alter table chat_histories add column conversation_id integer not null default 0;
+
+create index idx_chat_histories_conversation on chat_histories (conversation_id, id);
+
+alter table read_markers add column conversation_id integer not null default 0;
+
+drop index idx_read_markers_conversation;
+create unique index idx_read_markers_conversation on read_markers (user_id, room_id, peer_id, conversation_id);
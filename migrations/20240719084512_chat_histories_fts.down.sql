@@ -0,0 +1,5 @@
+drop trigger chat_histories_fts_update;
+drop trigger chat_histories_fts_delete;
+drop trigger chat_histories_fts_insert;
+
+drop table chat_histories_fts;
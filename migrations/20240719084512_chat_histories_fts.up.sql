@@ -0,0 +1,21 @@
+create virtual table chat_histories_fts using fts5 (
+    message,
+    content = 'chat_histories',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into chat_histories_fts (chat_histories_fts) values ('rebuild');
+
+create trigger chat_histories_fts_insert after insert on chat_histories begin
+    insert into chat_histories_fts (rowid, message) values (new.id, new.message);
+end;
+
+create trigger chat_histories_fts_delete after delete on chat_histories begin
+    insert into chat_histories_fts (chat_histories_fts, rowid, message) values ('delete', old.id, old.message);
+end;
+
+create trigger chat_histories_fts_update after update of message on chat_histories begin
+    insert into chat_histories_fts (chat_histories_fts, rowid, message) values ('delete', old.id, old.message);
+    insert into chat_histories_fts (rowid, message) values (new.id, new.message);
+end;
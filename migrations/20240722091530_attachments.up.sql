@@ -0,0 +1,13 @@
+create table attachments (
+    id integer primary key,
+    message_id integer not null default 0,
+    uploader_id integer not null,
+    file_name text not null,
+    content_type text not null,
+    size integer not null,
+    storage_key text not null,
+    created_at datetime not null
+);
+
+create unique index idx_attachments_storage_key on attachments (storage_key);
+create index idx_attachments_message on attachments (message_id);
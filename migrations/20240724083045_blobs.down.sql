@@ -0,0 +1,4 @@
+drop index idx_attachments_storage_key;
+create unique index idx_attachments_storage_key on attachments (storage_key);
+
+drop table blobs;
@@ -0,0 +1,13 @@
+create table blobs (
+    storage_key text primary key,
+    size integer not null,
+    created_at datetime not null,
+    last_used_at datetime not null,
+    deleted_at datetime
+);
+
+insert into blobs (storage_key, size, created_at, last_used_at)
+select storage_key, size, created_at, created_at from attachments;
+
+drop index idx_attachments_storage_key;
+create index idx_attachments_storage_key on attachments (storage_key);
@@ -0,0 +1,2 @@
+alter table chat_histories drop column message_meta;
+alter table chat_histories drop column message_type;
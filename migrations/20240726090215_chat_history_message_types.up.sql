@@ -0,0 +1,4 @@
+alter table chat_histories add column message_type text not null default 'text';
+alter table chat_histories add column message_meta text not null default '';
+
+update chat_histories set message_type = 'system' where sender_id = 0;
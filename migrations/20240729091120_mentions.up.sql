@@ -0,0 +1,9 @@
+create table mentions (
+    id integer primary key,
+    message_id integer not null,
+    user_id integer not null,
+    is_room_mention integer not null default 0,
+    created_at datetime not null
+);
+
+create unique index idx_mentions_user_message on mentions (user_id, message_id);
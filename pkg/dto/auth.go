@@ -1,7 +1,15 @@
 package dto
 
+import "time"
+
 type AuthLoginPayload struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
+type AuthSessionResponse struct {
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type ChatGroupPayload struct {
+	ConversationID int64    `json:"conversation_id"`
+	Usernames      []string `json:"usernames"`
+	AttachmentIDs  []int64  `json:"attachment_ids"`
+	MessageEnvelope
+}
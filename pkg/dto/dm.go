@@ -1,6 +1,7 @@
 package dto
 
 type ChatDMPayload struct {
-	RecipientUsername string `json:"recipient_username"`
-	Content           string `json:"content"`
+	RecipientUsername string  `json:"recipient_username"`
+	AttachmentIDs     []int64 `json:"attachment_ids"`
+	MessageEnvelope
 }
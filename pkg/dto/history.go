@@ -0,0 +1,12 @@
+package dto
+
+type ChatLogPayload struct {
+	RoomID         int64  `json:"room_id" query:"room_id"`
+	MessageID      int64  `json:"message_id" query:"message_id"`
+	ConversationID int64  `json:"conversation_id" query:"conversation_id"`
+	RoomName       string `json:"room_name" query:"room_name"`
+	Username       string `json:"username" query:"username"`
+	BeforeID       int64  `json:"before_id" query:"before_id"`
+	AfterID        int64  `json:"after_id" query:"after_id"`
+	Limit          uint64 `json:"limit" query:"limit"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type MentionListPayload struct {
+	RoomID   int64  `json:"room_id" query:"room_id"`
+	BeforeID int64  `json:"before_id" query:"before_id"`
+	Limit    uint64 `json:"limit" query:"limit"`
+}
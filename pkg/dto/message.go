@@ -0,0 +1,39 @@
+package dto
+
+// MessageEnvelope describe what is being sent, content is the message body for text, markdown and code
+// while it serve as plain text fallback of a card
+type MessageEnvelope struct {
+	Type     string       `json:"type"`
+	Content  string       `json:"content"`
+	Language string       `json:"language,omitempty"` // code only
+	Card     *MessageCard `json:"card,omitempty"`     // card only
+}
+
+type MessageCard struct {
+	Title    string               `json:"title"`
+	Text     string               `json:"text,omitempty"`
+	ImageURL string               `json:"image_url,omitempty"`
+	Fields   []*MessageCardField  `json:"fields,omitempty"`
+	Buttons  []*MessageCardButton `json:"buttons,omitempty"`
+}
+
+type MessageCardField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// MessageCardButton open the url on the client side, the server doesnt handle any button action
+type MessageCardButton struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+type MessageEditPayload struct {
+	MessageID int64  `json:"message_id"`
+	Content   string `json:"content"`
+}
+
+type MessageDeletePayload struct {
+	MessageID int64 `json:"message_id"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type RoomModerationPayload struct {
+	RoomID   int64  `json:"room_id"`
+	RoomName string `json:"room_name"`
+	Username string `json:"username"`
+	Duration int64  `json:"duration"` // mute duration in seconds
+	Role     string `json:"role"`
+}
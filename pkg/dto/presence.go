@@ -0,0 +1,15 @@
+package dto
+
+type PresenceSetPayload struct {
+	Status string `json:"status"`
+}
+
+type PresenceQueryPayload struct {
+	Usernames []string `json:"usernames"`
+}
+
+type LiveChatPresenceEvent struct {
+	UserID   int64
+	Username string
+	Status   string
+}
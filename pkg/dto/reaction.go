@@ -0,0 +1,6 @@
+package dto
+
+type MessageReactionPayload struct {
+	MessageID int64  `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type MessageAckPayload struct {
+	MessageID int64 `json:"message_id"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type BaseResponse struct {
+	Data  any    `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,54 @@
+package dto
+
+type ChatRoomPayload struct {
+	RoomID        int64   `json:"room_id"`
+	RoomName      string  `json:"room_name"`
+	AttachmentIDs []int64 `json:"attachment_ids"`
+	MessageEnvelope
+}
+
+type CreateRoomPayload struct {
+	RoomName    string `json:"room_name"`
+	Topic       string `json:"topic"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+// UpdateRoomPayload only update field which is present
+type UpdateRoomPayload struct {
+	RoomID      int64   `json:"room_id"`
+	RoomName    string  `json:"room_name"`
+	NewName     *string `json:"new_name"`
+	Topic       *string `json:"topic"`
+	Description *string `json:"description"`
+}
+
+type JoinRoomPayload struct {
+	RoomID     int64  `json:"room_id"`
+	RoomName   string `json:"room_name"`
+	InviteCode string `json:"invite_code"`
+}
+
+type RoomInvitePayload struct {
+	RoomID   int64  `json:"room_id"`
+	RoomName string `json:"room_name"`
+	Username string `json:"username"`
+	Reset    bool   `json:"reset"`
+}
+
+type RoomVisibilityPayload struct {
+	RoomID     int64  `json:"room_id"`
+	RoomName   string `json:"room_name"`
+	Visibility string `json:"visibility"`
+}
+
+type LeaveRoomPayload struct {
+	RoomID   int64  `json:"room_id"`
+	RoomName string `json:"room_name"`
+}
+
+type RoomDirectoryPayload struct {
+	Query  string `json:"query" query:"q"`
+	Limit  uint64 `json:"limit" query:"limit"`
+	Offset uint64 `json:"offset" query:"offset"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+type MessageSearchPayload struct {
+	Query          string `json:"query" query:"q"`
+	RoomID         int64  `json:"room_id" query:"room_id"`
+	RoomName       string `json:"room_name" query:"room_name"`
+	Username       string `json:"username" query:"username"`
+	ConversationID int64  `json:"conversation_id" query:"conversation_id"`
+	Sender         string `json:"sender" query:"sender"`
+	Since          string `json:"since" query:"since"`
+	Until          string `json:"until" query:"until"`
+	BeforeID       int64  `json:"before_id" query:"before_id"`
+	Limit          uint64 `json:"limit" query:"limit"`
+}
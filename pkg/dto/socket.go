@@ -1,13 +1,17 @@
 package dto
 
 type LiveChatSocketRequest struct {
-	SenderID    int64
-	RecipientID int64
-	Event       LiveChatSocketEvent
+	MessageID      int64
+	SenderID       int64
+	RecipientID    int64
+	RecipientIDs   []int64 // set instead of RecipientID for group conversation
+	ConversationID int64
+	Event          LiveChatSocketEvent
 }
 
 type LiveChatSocketEvent struct {
 	EventName string      `json:"event"`
+	RoomID    int64       `json:"room_id,omitempty"`
 	Data      interface{} `json:"data"`
 }
 
@@ -15,3 +19,8 @@ type LiveChatBroadcastEvent struct {
 	Room  int64
 	Event LiveChatSocketEvent
 }
+
+type LiveChatUserEvent struct {
+	UserID int64
+	Event  LiveChatSocketEvent
+}
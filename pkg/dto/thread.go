@@ -0,0 +1,7 @@
+package dto
+
+type ThreadReplyPayload struct {
+	MessageID     int64   `json:"message_id"`
+	AttachmentIDs []int64 `json:"attachment_ids"`
+	MessageEnvelope
+}
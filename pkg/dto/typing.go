@@ -0,0 +1,15 @@
+package dto
+
+type TypingPayload struct {
+	RoomID   int64  `json:"room_id"`
+	RoomName string `json:"room_name"`
+	Username string `json:"username"`
+}
+
+type LiveChatTypingEvent struct {
+	UserID   int64
+	Username string
+	RoomID   int64
+	PeerID   int64
+	IsTyping bool
+}
@@ -13,6 +13,13 @@ var (
 	ErrUnknown       = errors.New("internal server error")
 	ErrNotFound      = errors.New("entity not found")
 	ErrUserExisted   = errors.New("user already existed")
+	ErrInvalidToken  = errors.New("invalid or expired session token")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrRoomExisted   = errors.New("room already exists")
+	ErrMuted         = errors.New("user is muted")
+	ErrBanned        = errors.New("user is banned")
+	ErrInvalidMsg    = errors.New("invalid message")
 )
 
 type CustomError struct {
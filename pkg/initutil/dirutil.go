@@ -11,14 +11,14 @@ import (
 func InitDirectory() {
 	conf := config.Get()
 
-	sysDir := []string{"logs", "db"}
+	sysDir := []string{"logs", "db", "attachments"}
 
 	for _, dir := range sysDir {
 		joinDir := filepath.Join(conf.FilePath, dir)
 
 		_, err := os.Stat(joinDir)
 		if os.IsNotExist(err) {
-			os.Mkdir(joinDir, fs.ModeDir)
+			os.Mkdir(joinDir, fs.ModeDir|0o755)
 		}
 	}
 }
@@ -0,0 +1,75 @@
+package tokenutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken = errors.New("malformed token")
+	ErrInvalidSign    = errors.New("invalid token signature")
+	ErrTokenExpired   = errors.New("token expired")
+)
+
+type Claims struct {
+	SessionID string `json:"sid"`
+	UserID    int64  `json:"uid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Sign encode claims as `<payload>.<signature>` where signature is HMAC-SHA256 of the payload
+func Sign(secret []byte, claims *Claims) (token string, err error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	token = payload + "." + base64.RawURLEncoding.EncodeToString(sign(secret, payload))
+
+	return
+}
+
+// Verify check token signature and expiry, then return its claims
+func Verify(secret []byte, token string) (claims *Claims, err error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrMalformedToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if !hmac.Equal(sig, sign(secret, payload)) {
+		return nil, ErrInvalidSign
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	claims = &Claims{}
+	if err = json.Unmarshal(b, claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
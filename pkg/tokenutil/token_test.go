@@ -0,0 +1,78 @@
+package tokenutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+func mustSign(t *testing.T, secret []byte, claims *Claims) string {
+	t.Helper()
+
+	token, err := Sign(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestSignVerify(t *testing.T) {
+	claims := &Claims{SessionID: "session", UserID: 42, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	token := mustSign(t, testSecret, claims)
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// payload signed properly but carrying something else than claims
+	garbage := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+	garbage += "." + base64.RawURLEncoding.EncodeToString(sign(testSecret, garbage))
+
+	forged := mustSign(t, testSecret, &Claims{SessionID: "session", UserID: 1, ExpiresAt: claims.ExpiresAt})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	// flip the last byte of the signature
+	sig, _ := base64.RawURLEncoding.DecodeString(signature)
+	sig[len(sig)-1] ^= 0xff
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		err    error
+	}{
+		{name: "valid", secret: testSecret, token: token},
+		{name: "expired", secret: testSecret, token: mustSign(t, testSecret, &Claims{SessionID: "session", UserID: 42, ExpiresAt: time.Now().Add(-time.Second).Unix()}), err: ErrTokenExpired},
+		{name: "tampered payload", secret: testSecret, token: forgedPayload + "." + signature, err: ErrInvalidSign},
+		{name: "tampered signature", secret: testSecret, token: payload + "." + base64.RawURLEncoding.EncodeToString(sig), err: ErrInvalidSign},
+		{name: "truncated signature", secret: testSecret, token: payload + "." + signature[:len(signature)-4], err: ErrInvalidSign},
+		{name: "wrong secret", secret: []byte("other-secret"), token: token, err: ErrInvalidSign},
+		{name: "empty", secret: testSecret, token: "", err: ErrMalformedToken},
+		{name: "missing separator", secret: testSecret, token: payload, err: ErrMalformedToken},
+		{name: "extra separator", secret: testSecret, token: token + ".x", err: ErrMalformedToken},
+		{name: "signature not base64", secret: testSecret, token: payload + ".!!", err: ErrMalformedToken},
+		{name: "payload not claims", secret: testSecret, token: garbage, err: ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Verify(tt.secret, tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				if res != nil {
+					t.Fatalf("claims should not be returned on error, got %+v", res)
+				}
+				return
+			}
+
+			if *res != *claims {
+				t.Fatalf("expected %+v, got %+v", claims, res)
+			}
+		})
+	}
+}